@@ -0,0 +1,109 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// stripAnsi turns raw pty output into plain text by dropping escape
+// sequences and applying carriage returns and backspaces line by line.
+func stripAnsi(data []byte) string {
+	var out strings.Builder
+	line := make([]rune, 0, 128)
+	col := 0
+	put := func(r rune) {
+		if col < len(line) {
+			line[col] = r
+		} else {
+			line = append(line, r)
+		}
+		col++
+	}
+
+	for i := 0; i < len(data); {
+		b := data[i]
+		switch {
+		case b == 0x1b:
+			i = skipEscape(data, i)
+			continue
+		case b == '\n':
+			out.WriteString(strings.TrimRight(string(line), " "))
+			out.WriteByte('\n')
+			line = line[:0]
+			col = 0
+		case b == '\r':
+			col = 0
+		case b == '\b':
+			col = max(col-1, 0)
+		case b == '\t':
+			put('\t')
+		case b < 0x20 || b == 0x7f:
+		default:
+			r, size := utf8.DecodeRune(data[i:])
+			put(r)
+			i += size
+			continue
+		}
+		i++
+	}
+	out.WriteString(strings.TrimRight(string(line), " "))
+	return out.String()
+}
+
+// skipEscape returns the index after the escape sequence starting at i.
+func skipEscape(data []byte, i int) int {
+	end, _ := scanEscape(data, i)
+	return end
+}
+
+// scanEscape is skipEscape that also tells whether the sequence ends within
+// data, it is cut off when output arrives in pieces.
+func scanEscape(data []byte, i int) (int, bool) {
+	i++
+	if i >= len(data) {
+		return i, false
+	}
+	switch data[i] {
+	case '[': // CSI
+		for i++; i < len(data); i++ {
+			if data[i] >= 0x40 && data[i] <= 0x7e {
+				return i + 1, true
+			}
+		}
+	case ']', 'P', '_', '^', 'X': // OSC, DCS and other strings
+		for i++; i < len(data); i++ {
+			if data[i] == 0x07 {
+				return i + 1, true
+			}
+			if data[i] == 0x1b && i+1 < len(data) && data[i+1] == '\\' {
+				return i + 2, true
+			}
+		}
+	case '(', ')', '*', '+', '#', '%': // charset designation
+		return min(i+2, len(data)), i+2 <= len(data)
+	default:
+		return i + 1, true
+	}
+	return i, false
+}
+
+// plainOutput drops escape sequences and control characters other than
+// newlines and tabs without interpreting them, offsets maps every byte of
+// the result back to its index in data.
+func plainOutput(data []byte) ([]byte, []int) {
+	plain := make([]byte, 0, len(data))
+	offsets := make([]int, 0, len(data))
+	for i := 0; i < len(data); {
+		b := data[i]
+		if b == 0x1b {
+			i = skipEscape(data, i)
+			continue
+		}
+		if (b >= 0x20 && b != 0x7f) || b == '\n' || b == '\t' {
+			plain = append(plain, b)
+			offsets = append(offsets, i)
+		}
+		i++
+	}
+	return plain, offsets
+}
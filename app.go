@@ -16,8 +16,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	pty "github.com/UfukUstali/go-pty"
@@ -29,7 +31,7 @@ import (
 var (
 	logFile   *os.File
 	logger    *log.Logger
-	idCounter int = 0
+	idCounter atomic.Int64 // CreateTerminal is called from the frontend, remote clients and the API
 )
 
 var (
@@ -39,21 +41,42 @@ var (
 )
 
 type Terminal struct {
-	pty       pty.Pty
-	process   pty.Child
-	paused    bool
-	connected bool
-	cleanup   uint8
-	toggle    chan struct{}
-	read      chan []byte
-	write     chan []byte
-	ctx       context.Context
-	cancel    context.CancelCauseFunc
-	mutex     sync.Mutex
+	id              int
+	config          TerminalConfig
+	pty             pty.Pty
+	process         pty.Child
+	cmd             *exec.Cmd
+	cwd             string
+	paused          bool
+	connected       bool
+	cleanup         uint8
+	toggle          chan struct{}
+	read            chan []byte
+	write           chan []byte
+	ctx             context.Context
+	cancel          context.CancelCauseFunc
+	osc             *oscParser
+	scrollback      *scrollback
+	commands        *commandTracker
+	notifyLimiter   *rateLimiter
+	progress        Progress
+	clipboardPrompt bool
+	title           titleEntry
+	titleStack      []titleEntry
+	shownTitle      string
+	headless        bool          // created through the automation API, there is no tab
+	exitCode        *int          // set once the process exited
+	outputChanged   chan struct{} // closed and replaced on every output
+	prompted        chan struct{} // closed on the first prompt mark
+	readDone        chan struct{} // closed when the read thread stopped
+	expectOffset    int64         // where the next expect starts matching
+	mutex           sync.Mutex
+	writeMutex      sync.Mutex
 }
 
 type Terminals struct {
 	terminals map[int]*Terminal
+	active    int
 	mutex     sync.Mutex
 }
 
@@ -63,13 +86,58 @@ const (
 	TerminalsKey key = iota
 	FrontendAuthKey
 	WebsocketPortKey
+	HistoryKey
+	NotifierKey
+	ClipboardAuditKey
+	AutomationTokenKey
+	SessionKey
+	ClosedKey
 )
 
 type TerminalConfig struct {
-	Size    *PtySize `json:"size"`
-	Command string   `json:"command"`
-	Args    []string `json:"args"`
+	Size             *PtySize `json:"size"`
+	Command          string   `json:"command"`
+	Args             []string `json:"args"`
+	Cwd              *string  `json:"cwd"`
+	CwdVerbatim      bool     `json:"cwdVerbatim,omitempty"` // cwd is a path, not the profile's cwd to expand
+	InheritCwdFrom   *int     `json:"inheritCwdFrom"`
+	ShellIntegration *bool    `json:"shellIntegration"`
+	Profile          string   `json:"profile"`
+	Input            *string  `json:"input"`
+
+	CommandNotification *CommandNotification `json:"commandNotification"`
+	Notifications       *NotificationPolicy  `json:"notifications"`
+	Clipboard           string               `json:"clipboard,omitempty"`      // deny, write (default), read-prompt or all
+	TitleTemplate       string               `json:"titleTemplate,omitempty"`  // {title}, {profile}, {cwd} and {id} are replaced
+	Title               string               `json:"title,omitempty"`          // fixed, replaces the titles programs set
+	Verbatim            bool                 `json:"verbatim,omitempty"`       // command and args are not expanded, they come from the command line
+	Ticket              int                  `json:"ticket,omitempty"`         // reports the id to whoever requested the tab
+	StartupInput        string               `json:"startupInput,omitempty"`   // written before input, once the output matches startupPattern
+	StartupPattern      string               `json:"startupPattern,omitempty"` // regex
+	PreLaunch           []string             `json:"preLaunch,omitempty"`      // runs to completion before the shell starts, a failure aborts
+	PostExit            []string             `json:"postExit,omitempty"`       // runs after the shell exited, with $TERM2_EXIT_STATUS
+
+	Env         map[string]*string `json:"env,omitempty"` // null unsets a variable
+	EnvFiles    []string           `json:"envFiles,omitempty"`
+	PathPrepend []string           `json:"pathPrepend,omitempty"`
+	PathAppend  []string           `json:"pathAppend,omitempty"`
+
+	headless bool
+}
+
+// TerminalRequest asks the frontend to open a new tab, fields left empty
+// fall back to the profile.
+type TerminalRequest struct {
+	Profile string   `json:"profile"`
 	Cwd     *string  `json:"cwd"`
+	Input   *string  `json:"input"`
+	Title   string   `json:"title,omitempty"`
+	Command []string `json:"command,omitempty"` // replaces the profile's command and args
+	Ticket  int      `json:"ticket,omitempty"`  // passed on to TerminalConfig
+
+	Env        map[string]*string `json:"env,omitempty"`        // on top of the profile's
+	Scrollback []byte             `json:"scrollback,omitempty"` // output of a restored or reopened tab, base64 so it survives as is
+	ExpandCwd  bool               `json:"expandCwd,omitempty"`  // cwd comes from a workspace file and may use ~ and variables
 }
 
 type PtySize struct {
@@ -80,44 +148,63 @@ type PtySize struct {
 }
 
 type App struct {
-	ctx context.Context
-	dev bool
+	ctx            context.Context
+	dev            bool
+	paths          Paths
+	integrationDir string
+	config         *Config
+	configMutex    sync.Mutex
+	watchOnce      sync.Once
+	loginEnv       loginEnv
+	launch         *launchArgs  // from the command line, until the frontend asks for it
+	remote         net.Listener // remote control socket
+	socket         string
+	tabs           pendingTabs
 }
 
-func NewApp(dev bool) *App {
+func NewApp(dev bool, paths Paths) *App {
 	return &App{
-		dev: dev,
+		dev:   dev,
+		paths: paths,
 	}
 }
 
 func (a *App) startup(ctx context.Context) {
 	a.ctx = context.WithValue(ctx, TerminalsKey, &Terminals{
 		make(map[int]*Terminal),
+		-1,
 		sync.Mutex{},
 	})
 
+	a.integrationDir = filepath.Join(a.paths.Cache, "shell-integration")
+	if err := installShellIntegration(a.integrationDir); err != nil {
+		logger.Println(err)
+		a.integrationDir = ""
+	}
+
+	history, err := loadHistory(filepath.Join(a.paths.State, "history.jsonl"))
+	if err != nil {
+		logger.Println(err)
+	}
+	a.ctx = context.WithValue(a.ctx, HistoryKey, history)
+	a.ctx = context.WithValue(a.ctx, ClipboardAuditKey, &ClipboardAudit{path: filepath.Join(a.paths.State, "clipboard.log")})
+
+	notifier, err := newNotifier()
+	if err != nil {
+		logger.Println(err)
+	}
+	a.ctx = context.WithValue(a.ctx, NotifierKey, notifier)
+	a.ctx = context.WithValue(a.ctx, SessionKey, newSessionState())
+	a.ctx = context.WithValue(a.ctx, ClosedKey, a.loadClosed())
+
 	randBytes := make([]byte, 32)
 	rand.Read(randBytes)
 	a.ctx = context.WithValue(a.ctx, FrontendAuthKey, hex.EncodeToString(randBytes))
+	rand.Read(randBytes)
+	a.ctx = context.WithValue(a.ctx, AutomationTokenKey, hex.EncodeToString(randBytes))
 
-	var certFile string
-	var keyFile string
-	if a.dev {
-		certFile = "./certs/localhost.pem"
-		keyFile = "./certs/localhost-key.pem"
-	} else {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
-				Type:    runtime.ErrorDialog,
-				Title:   "Error",
-				Message: "Couldn't find HOMEDIR",
-			})
-			runtime.Quit(a.ctx)
-		}
-		certFile = homeDir + "/.term2/certs/localhost.pem"
-		keyFile = homeDir + "/.term2/certs/localhost-key.pem"
-	}
+	certFile := filepath.Join(a.paths.Certs(), "localhost.pem")
+	keyFile := filepath.Join(a.paths.Certs(), "localhost-key.pem")
 
 	if _, err := os.Stat(certFile); errors.Is(err, fs.ErrNotExist) {
 		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
@@ -320,6 +407,8 @@ func (a *App) startup(ctx context.Context) {
 		}()
 	})
 
+	a.automationRoutes(mux)
+
 	port := 34373
 	for ; port < 65535; port++ {
 		listener, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
@@ -339,6 +428,10 @@ func (a *App) startup(ctx context.Context) {
 	}
 	a.ctx = context.WithValue(a.ctx, WebsocketPortKey, port)
 
+	// a.ctx is complete, goroutines and remote clients may use it from here on
+	go a.watchSession()
+	a.listenRemote()
+
 	server := http.Server{
 		Addr:      fmt.Sprintf("localhost:%d", port),
 		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
@@ -379,12 +472,17 @@ func (a *App) startup(ctx context.Context) {
 					delete(terminals.terminals, id)
 				}
 				terminals.mutex.Unlock()
+				if len(ids) > 0 {
+					updateWindowTitle(a.ctx)
+				}
 			}
 		}
 	}()
 }
 
 func (a *App) shutdown(_ context.Context) {
+	a.saveSession(true)
+
 	terminals := a.ctx.Value(TerminalsKey).(*Terminals)
 	ids := make([]int, 0, len(terminals.terminals))
 
@@ -397,6 +495,15 @@ func (a *App) shutdown(_ context.Context) {
 		delete(terminals.terminals, id)
 	}
 	terminals.mutex.Unlock()
+
+	a.ctx.Value(HistoryKey).(*History).Flush()
+	if notifier, _ := a.ctx.Value(NotifierKey).(*Notifier); notifier != nil {
+		notifier.Close()
+	}
+	if a.remote != nil {
+		a.remote.Close()
+	}
+	a.configureAutomation(nil)
 }
 
 func (a *App) GetDetails(lastId int) string {
@@ -404,8 +511,8 @@ func (a *App) GetDetails(lastId int) string {
 		terminals := a.ctx.Value(TerminalsKey).(*Terminals)
 		terminals.mutex.Lock()
 		ids := make([]int, 0, len(terminals.terminals)-1)
-		for id := range terminals.terminals {
-			if id == lastId {
+		for id, term := range terminals.terminals {
+			if id == lastId || term.headless {
 				continue
 			}
 			ids = append(ids, id)
@@ -415,13 +522,54 @@ func (a *App) GetDetails(lastId int) string {
 			delete(terminals.terminals, id)
 		}
 		terminals.mutex.Unlock()
+		updateWindowTitle(a.ctx)
 	}()
 	return fmt.Sprintf("%v:%v", (a.ctx.Value(FrontendAuthKey)), (a.ctx.Value(WebsocketPortKey)))
 }
 
-func (a *App) CreateTerminal(config TerminalConfig) (int, error) {
-	id := idCounter
-	idCounter++
+func (a *App) CreateTerminal(config TerminalConfig) (id int, err error) {
+	if config.Ticket != 0 {
+		defer func() {
+			a.tabs.resolve(config.Ticket, id, err)
+		}()
+	}
+
+	id = int(idCounter.Add(1) - 1)
+
+	env, err := a.buildEnv(a.loginEnv.environ(), config)
+	if err == nil {
+		lookup := func(name string) (string, bool) {
+			return lookupEnv(env, name)
+		}
+		err = newExpander(config.Profile, a.paths.Config, lookup).expandConfig(&config)
+	}
+	// a command from the command line or a request isn't the profile's shell
+	startup := config.StartupInput != "" && !config.Verbatim
+	var pattern *regexp.Regexp
+	if err == nil && startup && config.StartupPattern != "" {
+		pattern, err = regexp.Compile(config.StartupPattern)
+	}
+	if err != nil {
+		err = fmt.Errorf("profile %q: %w", config.Profile, err)
+		logger.Println(err)
+		return -1, err
+	}
+
+	dir := ""
+	if config.InheritCwdFrom != nil {
+		dir = a.terminalCwd(*config.InheritCwdFrom)
+	}
+	if dir == "" {
+		dir = a.defaultCwd(config)
+	}
+
+	if len(config.PreLaunch) > 0 {
+		if err := runHook(config.PreLaunch, dir, env); err != nil {
+			err = fmt.Errorf("preLaunch of profile %q failed: %w", config.Profile, err)
+			logger.Println(err)
+			return -1, err
+		}
+	}
 
 	var size pty.PtySize
 	if config.Size != nil {
@@ -441,24 +589,18 @@ func (a *App) CreateTerminal(config TerminalConfig) (int, error) {
 	}
 
 	cmd := exec.Command(config.Command, config.Args...)
-	if config.Cwd != nil {
-		cmd.Dir = *config.Cwd
-	} else if a.dev {
-		dir, err := os.Getwd()
-		if err != nil {
-			logger.Println(err)
-		} else {
-			cmd.Dir = dir
-		}
-	} else {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			logger.Println(err)
-		} else {
-			cmd.Dir = homeDir
-		}
+	if path, ok := lookPath(config.Command, env); ok {
+		cmd.Path, cmd.Err = path, nil
+	}
+	cmd.Dir = dir
+	cmd.Env = append(env, "TERM_PROGRAM=term2", "TERM=xterm-256color", remoteSessionEnv+"="+strconv.Itoa(id))
+	if a.socket != "" {
+		cmd.Env = append(cmd.Env, remoteSocketEnv+"="+a.socket)
+	}
+	integrated := false
+	if a.integrationDir != "" && (config.ShellIntegration == nil || *config.ShellIntegration) {
+		integrated = injectShellIntegration(cmd, a.integrationDir)
 	}
-	cmd.Env = append(cmd.Environ(), "TERM_PROGRAM=term2", "TERM=xterm-256color")
 
 	process, err := pty.SpawnCommand(cmd)
 	if err != nil {
@@ -485,25 +627,50 @@ func (a *App) CreateTerminal(config TerminalConfig) (int, error) {
 	ctx, cancel := context.WithCancelCause(a.ctx)
 
 	term := &Terminal{
-		pty,
-		process,
-		true,
-		false,
-		0,
-		toggle,
-		read,
-		write,
-		ctx,
-		cancel,
-		sync.Mutex{},
+		id:            id,
+		config:        config,
+		pty:           pty,
+		process:       process,
+		cmd:           cmd,
+		paused:        !config.headless,
+		connected:     config.headless,
+		headless:      config.headless,
+		outputChanged: make(chan struct{}),
+		readDone:      make(chan struct{}),
+		prompted:      make(chan struct{}),
+		toggle:        toggle,
+		read:          read,
+		write:         write,
+		ctx:           ctx,
+		cancel:        cancel,
+		scrollback:    newScrollback(scrollbackLimit),
+		commands:      newCommandTracker(),
+		notifyLimiter: newRateLimiter(3, 10*time.Second),
+		progress:      Progress{State: "none"},
 	}
+	term.osc = newOscParser(term.handleOsc, term.handleCsi, term.handleBell)
 
-	go waitThread(ctx, id, term)
+	go a.waitThread(ctx, id, term)
 
-	go readThread(ctx, reader, read, toggle)
+	go func() {
+		readThread(ctx, reader, read, toggle, !config.headless, term.handleOutput)
+		close(term.readDone)
+	}()
 
 	go writeThread(ctx, writer, write)
 
+	if config.headless {
+		go term.drain()
+	}
+
+	if startup || config.Input != nil {
+		go term.sendInput(startup, pattern, integrated)
+	}
+
+	if cmd.Process != nil {
+		go term.watchForeground(cmd.Process.Pid)
+	}
+
 	terminals := a.ctx.Value(TerminalsKey).(*Terminals)
 
 	terminals.mutex.Lock()
@@ -513,47 +680,183 @@ func (a *App) CreateTerminal(config TerminalConfig) (int, error) {
 	return id, nil
 }
 
-func (a *App) ConsoleLog(message string) {
-	logger.Println(message)
-}
-
-func (a *App) ReadConfigFile() string {
-	var fileAddress string
-	var err error
+func (a *App) defaultCwd(config TerminalConfig) string {
+	if config.Cwd != nil {
+		return *config.Cwd
+	}
 	if a.dev {
-		fileAddress = "./config.json"
-	} else {
-		fileAddress, err = os.UserHomeDir()
+		dir, err := os.Getwd()
 		if err != nil {
-			runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
-				Type:    runtime.ErrorDialog,
-				Title:   "Error",
-				Message: "Couldn't find HOMEDIR",
-			})
-			runtime.Quit(a.ctx)
+			logger.Println(err)
 			return ""
 		}
-		fileAddress += "/.term2/config.json"
+		return dir
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		logger.Println(err)
+		return ""
 	}
+	return homeDir
+}
 
-	file, err := os.ReadFile(fileAddress)
+// terminalCwd resolves the live working directory of a terminal, preferring
+// the last OSC 7 report over the foreground process. An empty string means
+// the directory is unknown or no longer exists.
+func (a *App) terminalCwd(id int) string {
+	term, err := a.terminal(id)
 	if err != nil {
-		if errors.Is(err, fs.ErrNotExist) {
-			err = os.WriteFile(fileAddress, []byte(DefaultKeybinds), 0644)
-			if err != nil {
-				runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
-					Type:    runtime.ErrorDialog,
-					Title:   "Error",
-					Message: "Couldn't create default config file",
-				})
-				runtime.Quit(a.ctx)
-				return ""
+		return ""
+	}
+
+	candidates := make([]string, 0, 3)
+	term.mutex.Lock()
+	candidates = append(candidates, term.cwd)
+	term.mutex.Unlock()
+	if term.cmd.Process != nil {
+		if cwd, err := processCwd(term.cmd.Process.Pid); err == nil {
+			candidates = append(candidates, cwd)
+		}
+	}
+	candidates = append(candidates, term.cmd.Dir)
+
+	for _, dir := range candidates {
+		if dir == "" {
+			continue
+		}
+		if stats, err := os.Stat(dir); err == nil && stats.IsDir() {
+			return dir
+		}
+		logger.Printf("Ignoring missing cwd %s of terminal %d\n", dir, id)
+	}
+	return ""
+}
+
+func (a *App) terminal(id int) (*Terminal, error) {
+	terminals := a.ctx.Value(TerminalsKey).(*Terminals)
+	terminals.mutex.Lock()
+	defer terminals.mutex.Unlock()
+	term, ok := terminals.terminals[id]
+	if !ok {
+		return nil, fmt.Errorf("terminal %d not found", id)
+	}
+	return term, nil
+}
+
+// send queues input for the pty, it is safe to call after the terminal closed.
+func (t *Terminal) send(data []byte) error {
+	t.writeMutex.Lock()
+	defer t.writeMutex.Unlock()
+	if t.ctx.Err() != nil {
+		return fmt.Errorf("terminal %d is closed", t.id)
+	}
+	select {
+	case t.write <- data:
+		return nil
+	case <-t.ctx.Done():
+		return fmt.Errorf("terminal %d is closed", t.id)
+	}
+}
+
+func (t *Terminal) handleOutput(data []byte) {
+	t.mutex.Lock()
+	t.scrollback.Write(data)
+	close(t.outputChanged)
+	t.outputChanged = make(chan struct{})
+	t.mutex.Unlock()
+	t.osc.Feed(data)
+}
+
+func (t *Terminal) handleOsc(code string, payload []byte) {
+	switch code {
+	case "7": // cwd
+		if cwd, ok := parseOsc7(payload); ok {
+			t.mutex.Lock()
+			t.cwd = cwd
+			t.mutex.Unlock()
+			t.updateTitle()
+			touchSession(t.ctx)
+		}
+	case "133": // prompt and command marks
+		t.handleCommandMark(payload)
+	case "9":
+		if params, ok := bytes.CutPrefix(payload, []byte("4;")); ok { // progress
+			if progress, ok := parseProgress(params); ok {
+				t.setProgress(progress)
 			}
-			return DefaultKeybinds
+			return
 		}
-		return ""
+		t.handleNotifyOsc(code, payload)
+	case "777": // notifications
+		t.handleNotifyOsc(code, payload)
+	case "0", "1", "2": // icon name and title
+		t.handleTitleOsc(code, payload)
+	case "52": // clipboard
+		t.handleClipboardOsc(payload)
+	}
+}
+
+func (a *App) ConsoleLog(message string) {
+	logger.Println(message)
+}
+
+// ReadConfigFile parses and validates the config file and starts watching
+// it for changes.
+func (a *App) ReadConfigFile() (*Config, error) {
+	if err := a.createDefaultConfig(); err != nil {
+		return nil, err
+	}
+	config, files, err := a.loadConfig()
+	if err := writeSchema(a.paths.Config); err != nil {
+		logger.Println(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.configMutex.Lock()
+	a.config = config
+	a.configMutex.Unlock()
+	a.loginEnv.configure(config.LoginShellEnv, a.loginEnvCacheFile())
+	a.configureAutomation(config.Automation)
+	a.watchOnce.Do(func() {
+		go a.watchConfig(files)
+	})
+	return config, nil
+}
+
+// createDefaultConfig writes the default config when there is none yet, only
+// on startup so a file that is briefly gone while an editor saves it isn't
+// overwritten.
+func (a *App) createDefaultConfig() error {
+	fileAddress := a.paths.ConfigFile()
+	if _, err := os.Stat(fileAddress); !errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	err := os.MkdirAll(a.paths.Config, 0755)
+	if err == nil {
+		err = os.WriteFile(fileAddress, []byte(DefaultKeybinds), 0644)
+	}
+	if err != nil {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:    runtime.ErrorDialog,
+			Title:   "Error",
+			Message: "Couldn't create default config file",
+		})
+		runtime.Quit(a.ctx)
 	}
-	return string(file)
+	return err
+}
+
+// loadConfig reads the config file. The files that were read are returned
+// even when the config is invalid so they can be watched.
+func (a *App) loadConfig() (*Config, []string, error) {
+	fileAddress := a.paths.ConfigFile()
+
+	file, err := os.ReadFile(fileAddress)
+	if err != nil {
+		return nil, []string{fileAddress}, err
+	}
+	return parseConfig(fileAddress, file)
 }
 
 func (a *App) ExitWithErr(msg string) {
@@ -566,39 +869,14 @@ func (a *App) ExitWithErr(msg string) {
 }
 
 func (a *App) OpenConfigFile() {
-	var fileAddress string
-	var err error
-	if a.dev {
-		cwd, err := os.Getwd()
-		if err != nil {
-			runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
-				Type:    runtime.ErrorDialog,
-				Title:   "Error",
-				Message: "Couldn't find CWD",
-			})
-			runtime.Quit(a.ctx)
-			return
-		}
-		fileAddress = filepath.Join(cwd, "./config.json")
-	} else {
-		fileAddress, err = os.UserHomeDir()
-		if err != nil {
-			runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
-				Type:    runtime.ErrorDialog,
-				Title:   "Error",
-				Message: "Couldn't find HOMEDIR",
-			})
-			runtime.Quit(a.ctx)
-			return
-		}
-		fileAddress += "/.term2/config.json"
-	}
-	open.Start(fileAddress)
+	open.Start(a.paths.ConfigFile())
 }
 
-func readThread(c context.Context, r io.Reader, channel chan<- []byte, toggle <-chan struct{}) {
+func readThread(c context.Context, r io.Reader, channel chan<- []byte, toggle <-chan struct{}, paused bool, output func([]byte)) {
 	defer close(channel)
-	<-toggle
+	if paused {
+		<-toggle
+	}
 	buf := make([]byte, 4096)
 	for {
 		select {
@@ -614,6 +892,7 @@ func readThread(c context.Context, r io.Reader, channel chan<- []byte, toggle <-
 				}
 				return
 			}
+			output(buf[:n])
 			select {
 			case channel <- buf[:n]:
 				continue
@@ -645,25 +924,50 @@ func writeThread(c context.Context, w io.Writer, channel <-chan []byte) {
 	}
 }
 
-func waitThread(c context.Context, id int, term *Terminal) {
+func (a *App) waitThread(c context.Context, id int, term *Terminal) {
 	go func() {
-		term.process.Wait()
+		code, err := term.process.Wait()
+		if err != nil {
+			logger.Println(err)
+		} else {
+			exitCode := int(code)
+			term.mutex.Lock()
+			term.exitCode = &exitCode
+			term.mutex.Unlock()
+		}
+		if term.headless {
+			// the last output is what expect clients wait for
+			term.settle()
+		}
 		term.cancel(causeProcessAwait)
+		if len(term.config.PostExit) > 0 {
+			term.postExit()
+		}
 	}()
 
 	<-c.Done()
 
-	cause := c.Err()
+	// c.Err() is always context.Canceled, only the cause tells a shutdown or
+	// an exited process from a closed tab
+	cause := context.Cause(c)
+
+	if cause != causeClosingMultiple && !term.headless {
+		a.recordClosed(term)
+	}
 
-	if cause != causeClosingMultiple {
+	// headless terminals keep their output and exit code until they are closed
+	if cause != causeClosingMultiple && !(term.headless && cause == causeProcessAwait) {
 		terminals := c.Value(TerminalsKey).(*Terminals)
 		terminals.mutex.Lock()
 		delete(terminals.terminals, id)
 		terminals.mutex.Unlock()
+		updateWindowTitle(c)
 	}
 
 	close(term.toggle)
+	term.writeMutex.Lock()
 	close(term.write)
+	term.writeMutex.Unlock()
 
 	if cause != causeProcessAwait {
 		term.process.Kill()
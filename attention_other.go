@@ -0,0 +1,12 @@
+//go:build !windows
+
+package main
+
+import "context"
+
+// requestAttention has no way to set the urgency hint or bounce the dock
+// icon through wails, raising the window instead would steal focus. The
+// caller falls back to a toast.
+func requestAttention(_ context.Context) bool {
+	return false
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procFlashWindowEx = windows.NewLazySystemDLL("user32.dll").NewProc("FlashWindowEx")
+
+const (
+	flashwTray      = 0x2
+	flashwTimerNoFg = 0xc
+)
+
+type flashWInfo struct {
+	size    uint32
+	hwnd    windows.HWND
+	flags   uint32
+	count   uint32
+	timeout uint32
+}
+
+// requestAttention flashes the taskbar button until the window is focused.
+func requestAttention(_ context.Context) bool {
+	hwnd := mainWindow()
+	if hwnd == 0 {
+		logger.Println("Couldn't find the main window to flash")
+		return false
+	}
+	info := flashWInfo{
+		hwnd:  hwnd,
+		flags: flashwTray | flashwTimerNoFg,
+	}
+	info.size = uint32(unsafe.Sizeof(info))
+	procFlashWindowEx.Call(uintptr(unsafe.Pointer(&info)))
+	return true
+}
+
+// mainWindow finds the wails window owned by this process.
+func mainWindow() windows.HWND {
+	pid := uint32(os.Getpid())
+	var found windows.HWND
+	class := make([]uint16, 64)
+	windows.EnumWindows(windows.NewCallback(func(hwnd windows.HWND, _ uintptr) uintptr {
+		var owner uint32
+		windows.GetWindowThreadProcessId(hwnd, &owner)
+		if owner != pid {
+			return 1
+		}
+		n, err := windows.GetClassName(hwnd, &class[0], int32(len(class)))
+		if err == nil && windows.UTF16ToString(class[:n]) == "wailsWindow" {
+			found = hwnd
+			return 0
+		}
+		return 1
+	}), nil)
+	return found
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	automationFile           = "automation.json"
+	defaultAutomationTimeout = 10 // seconds
+	maxAutomationInput       = 1 << 20
+
+	settleQuiet = 200 * time.Millisecond // without output after a headless process exited
+	settleLimit = 2 * time.Second
+
+	expectWindow = 64 << 10 // how far before new output a match may start
+)
+
+// Automation enables the HTTP API for driving terminals from scripts and
+// tests. Clients find the URL and token in automation.json in the state
+// directory, the token changes every time term2 starts.
+type Automation struct {
+	Enabled bool `json:"enabled"`
+}
+
+// automationCreate asks for a terminal, either from a profile with the
+// overrides of a TerminalRequest or from a complete TerminalConfig.
+type automationCreate struct {
+	TerminalRequest
+	Config *TerminalConfig `json:"config,omitempty"`
+	Size   *PtySize        `json:"size,omitempty"`
+	Show   bool            `json:"show,omitempty"` // open a tab in the window instead of running headless
+}
+
+type automationWait struct {
+	Pattern string  `json:"pattern,omitempty"`
+	Exit    bool    `json:"exit,omitempty"`    // wait for the process to exit instead
+	Timeout float64 `json:"timeout,omitempty"` // seconds
+}
+
+// ExpectMatch is the result of waiting for a pattern, like pexpect's before,
+// after and match groups.
+type ExpectMatch struct {
+	Before string   `json:"before"`
+	Match  string   `json:"match"`
+	Groups []string `json:"groups"`
+}
+
+// configureAutomation publishes the URL and token for clients while the API
+// is enabled.
+func (a *App) configureAutomation(settings *Automation) {
+	file := filepath.Join(a.paths.State, automationFile)
+	if settings == nil || !settings.Enabled {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logger.Println(err)
+		}
+		return
+	}
+	data, _ := json.MarshalIndent(map[string]string{
+		"url":   fmt.Sprintf("https://localhost:%d/api", a.ctx.Value(WebsocketPortKey)),
+		"token": a.ctx.Value(AutomationTokenKey).(string),
+	}, "", "  ")
+	if err := os.MkdirAll(a.paths.State, 0755); err != nil {
+		logger.Println(err)
+		return
+	}
+	if err := os.WriteFile(file, append(data, '\n'), 0600); err != nil {
+		logger.Println(err)
+	}
+}
+
+func (a *App) automationRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/terminals", a.automation(a.apiList))
+	mux.HandleFunc("POST /api/terminals", a.automation(a.apiCreate))
+	mux.HandleFunc("GET /api/terminals/{id}", a.automation(a.apiStatus))
+	mux.HandleFunc("DELETE /api/terminals/{id}", a.automation(a.apiClose))
+	mux.HandleFunc("POST /api/terminals/{id}/input", a.automation(a.apiInput))
+	mux.HandleFunc("GET /api/terminals/{id}/text", a.automation(a.apiText))
+	mux.HandleFunc("POST /api/terminals/{id}/wait", a.automation(a.apiWait))
+}
+
+// automation rejects requests while the API is disabled or without the token.
+func (a *App) automation(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		a.configMutex.Lock()
+		enabled := a.config != nil && a.config.Automation != nil && a.config.Automation.Enabled
+		a.configMutex.Unlock()
+		if !enabled {
+			respondError(w, http.StatusNotFound, errors.New("automation is disabled"))
+			return
+		}
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		expected := a.ctx.Value(AutomationTokenKey).(string)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			respondError(w, http.StatusUnauthorized, errors.New("bad or missing token"))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func respondJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		logger.Println(err)
+	}
+}
+
+func respondError(w http.ResponseWriter, status int, err error) {
+	respondJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// apiTerminal looks up the terminal of the request, it responds itself when
+// there is none.
+func (a *App) apiTerminal(w http.ResponseWriter, r *http.Request) *Terminal {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, errors.New("bad id"))
+		return nil
+	}
+	term, err := a.terminal(id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, err)
+		return nil
+	}
+	return term
+}
+
+func (a *App) apiList(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, a.remoteList())
+}
+
+func (a *App) apiStatus(w http.ResponseWriter, r *http.Request) {
+	if term := a.apiTerminal(w, r); term != nil {
+		respondJSON(w, http.StatusOK, a.describeTerminal(term))
+	}
+}
+
+func (a *App) apiCreate(w http.ResponseWriter, r *http.Request) {
+	var request automationCreate
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if request.Show {
+		if request.Config != nil || request.Size != nil {
+			respondError(w, http.StatusBadRequest, errors.New("config and size can't be used with show"))
+			return
+		}
+		id, err := a.remoteNewTab(&request.TerminalRequest)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err)
+			return
+		}
+		respondJSON(w, http.StatusCreated, map[string]int{"id": id})
+		return
+	}
+
+	var config TerminalConfig
+	if request.Config != nil {
+		config = *request.Config
+	} else {
+		profile, err := a.profile(request.Profile)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return
+		}
+		config = profileTerminalConfig(profile)
+		if request.Cwd != nil {
+			config.Cwd = request.Cwd
+			config.CwdVerbatim = true
+		}
+		config.Input = request.Input
+		config.Title = request.Title
+		if len(request.Command) > 0 {
+			config.Command, config.Args = request.Command[0], request.Command[1:]
+			config.Verbatim = true
+		}
+	}
+	config.Size = request.Size
+	config.Ticket = 0
+	config.headless = true
+
+	id, err := a.CreateTerminal(config)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, map[string]int{"id": id})
+}
+
+func (a *App) apiClose(w http.ResponseWriter, r *http.Request) {
+	term := a.apiTerminal(w, r)
+	if term == nil {
+		return
+	}
+	a.closeTerminal(term)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *App) apiInput(w http.ResponseWriter, r *http.Request) {
+	term := a.apiTerminal(w, r)
+	if term == nil {
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxAutomationInput))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := term.send(data); err != nil {
+		respondError(w, http.StatusConflict, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *App) apiText(w http.ResponseWriter, r *http.Request) {
+	term := a.apiTerminal(w, r)
+	if term == nil {
+		return
+	}
+	text, err := term.text(r.URL.Query().Get("extent"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, text)
+}
+
+func (a *App) apiWait(w http.ResponseWriter, r *http.Request) {
+	term := a.apiTerminal(w, r)
+	if term == nil {
+		return
+	}
+	request := automationWait{Timeout: defaultAutomationTimeout}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	if request.Timeout <= 0 {
+		respondError(w, http.StatusBadRequest, errors.New("timeout must be positive"))
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(request.Timeout*float64(time.Second)))
+	defer cancel()
+
+	if request.Exit {
+		select {
+		case <-term.ctx.Done():
+			// the exit code is set right before a process that exited on its own cancels
+			respondJSON(w, http.StatusOK, a.describeTerminal(term))
+		case <-ctx.Done():
+			respondError(w, http.StatusRequestTimeout, fmt.Errorf("terminal %d is still running", term.id))
+		}
+		return
+	}
+
+	if request.Pattern == "" {
+		respondError(w, http.StatusBadRequest, errors.New("pattern or exit is required"))
+		return
+	}
+	pattern, err := regexp.Compile(request.Pattern)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	match, err := term.expect(ctx, pattern)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		respondError(w, http.StatusRequestTimeout, fmt.Errorf("no match for %q", request.Pattern))
+	case err != nil:
+		respondError(w, http.StatusConflict, err)
+	default:
+		respondJSON(w, http.StatusOK, match)
+	}
+}
+
+// profile finds a profile of the current config, the default one for an
+// empty name.
+func (a *App) profile(name string) (Profile, error) {
+	a.configMutex.Lock()
+	defer a.configMutex.Unlock()
+	if a.config == nil {
+		return Profile{}, errors.New("the config isn't loaded yet")
+	}
+	if name == "" {
+		name = a.config.DefaultProfile
+	}
+	for _, profile := range a.config.Profiles {
+		if profile.Name == name {
+			return profile, nil
+		}
+	}
+	return Profile{}, fmt.Errorf("unknown profile %q", name)
+}
+
+// profileTerminalConfig fills a TerminalConfig like the frontend does for a
+// new tab.
+func profileTerminalConfig(profile Profile) TerminalConfig {
+	return TerminalConfig{
+		Command:             profile.Command,
+		Args:                profile.Args,
+		Cwd:                 profile.Cwd,
+		ShellIntegration:    profile.ShellIntegration,
+		Profile:             profile.Name,
+		CommandNotification: profile.CommandNotification,
+		Notifications:       profile.Notifications,
+		Clipboard:           profile.Clipboard,
+		TitleTemplate:       profile.TitleTemplate,
+		Env:                 profile.Env,
+		EnvFiles:            profile.EnvFiles,
+		PathPrepend:         profile.PathPrepend,
+		PathAppend:          profile.PathAppend,
+		StartupInput:        profile.StartupInput,
+		StartupPattern:      profile.StartupPattern,
+		PreLaunch:           profile.PreLaunch,
+		PostExit:            profile.PostExit,
+	}
+}
+
+// closeTerminal kills a terminal, headless ones that already exited are
+// only forgotten.
+func (a *App) closeTerminal(term *Terminal) {
+	if term.ctx.Err() == nil {
+		term.cancel(causeFrontendClose)
+		return
+	}
+	terminals := a.ctx.Value(TerminalsKey).(*Terminals)
+	terminals.mutex.Lock()
+	delete(terminals.terminals, term.id)
+	terminals.mutex.Unlock()
+}
+
+// drain stands in for the frontend of a headless terminal, its reader starts
+// unpaused so nothing is sent on toggle while waitThread may close it.
+func (t *Terminal) drain() {
+	for range t.read {
+	}
+}
+
+// settle waits for the output an exited process left in the pty, until the
+// reader hits EOF or the output stays quiet for a moment. Windows only ends
+// the output when the pty is closed, so it always takes the quiet period.
+func (t *Terminal) settle() {
+	limit := time.After(settleLimit)
+	for {
+		t.mutex.Lock()
+		changed := t.outputChanged
+		t.mutex.Unlock()
+		select {
+		case <-t.readDone:
+			return
+		case <-changed:
+		case <-time.After(settleQuiet):
+			return
+		case <-limit:
+			return
+		}
+	}
+}
+
+// expect waits until pattern matches the output after the previous match,
+// escape sequences and carriage returns are removed before matching.
+func (t *Terminal) expect(ctx context.Context, pattern *regexp.Regexp) (*ExpectMatch, error) {
+	return t.expectFrom(ctx, pattern, &t.expectOffset)
+}
+
+// expectFrom is expect with its own offset, guarded by the terminal's mutex.
+func (t *Terminal) expectFrom(ctx context.Context, pattern *regexp.Regexp, offset *int64) (*ExpectMatch, error) {
+	var seen plainText
+	exited := false
+	for {
+		t.mutex.Lock()
+		seen.update(t.scrollback, *offset)
+		changed := t.outputChanged
+		if m := seen.find(pattern); m != nil {
+			plain := seen.text
+			match := &ExpectMatch{
+				Before: string(plain[:m[0]]),
+				Match:  string(plain[m[0]:m[1]]),
+				Groups: []string{},
+			}
+			for i := 2; i < len(m); i += 2 {
+				group := ""
+				if m[i] >= 0 {
+					group = string(plain[m[i]:m[i+1]])
+				}
+				match.Groups = append(match.Groups, group)
+			}
+			if m[1] > 0 {
+				*offset = seen.offsets[m[1]-1] + 1
+			}
+			t.mutex.Unlock()
+			return match, nil
+		}
+		t.mutex.Unlock()
+
+		if exited {
+			return nil, fmt.Errorf("terminal %d exited", t.id)
+		}
+		select {
+		case <-changed:
+		case <-t.ctx.Done():
+			exited = true // one more look at what came last
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// plainText is the output an expect has stripped so far, a change in the
+// output only strips and searches what is new.
+type plainText struct {
+	text     []byte
+	offsets  []int64 // scrollback position of every byte of text
+	end      int64   // scrollback position stripped up to
+	searched int     // length of text at the last search, -1 before the first
+	started  bool
+}
+
+// update strips the output after what was seen, from is where it starts the
+// first time. An escape sequence cut off at the end waits for the rest.
+func (p *plainText) update(s *scrollback, from int64) {
+	if !p.started || p.end < s.start {
+		*p = plainText{end: max(from, s.start), searched: -1, started: true}
+	}
+	if n, _ := slices.BinarySearch(p.offsets, s.start); n > 0 {
+		// dropped from the scrollback, the match still finds what it saw
+		p.text, p.offsets = p.text[n:], p.offsets[n:]
+		p.searched = max(p.searched-n, 0)
+	}
+	output, _ := s.Slice(p.end, s.End())
+	if i := bytes.LastIndexByte(output, 0x1b); i >= 0 {
+		if _, complete := scanEscape(output, i); !complete {
+			output = output[:i]
+		}
+	}
+	plain, offsets := plainOutput(output)
+	p.text = append(p.text, plain...)
+	for _, o := range offsets {
+		p.offsets = append(p.offsets, p.end+int64(o))
+	}
+	p.end += int64(len(output))
+}
+
+// find searches the text again from expectWindow bytes before what is new,
+// the indexes are into text.
+func (p *plainText) find(pattern *regexp.Regexp) []int {
+	if p.searched == len(p.text) {
+		return nil
+	}
+	from := max(p.searched-expectWindow, 0)
+	p.searched = len(p.text)
+	m := pattern.FindSubmatchIndex(p.text[from:])
+	for i := range m {
+		if m[i] >= 0 {
+			m[i] += from
+		}
+	}
+	return m
+}
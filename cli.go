@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const configUsage = `usage: term2 [--config-dir <dir>] [--portable] config <command>
+
+commands:
+  schema                     print the JSON Schema of config.json
+  validate [file]            check a config like the app does, exits with 1 when it is invalid
+  print-default              print the config term2 creates when there is none
+  migrate [--dry-run] [file] upgrade a config to the current version, the old file is kept as <file>.bak
+`
+
+// runConfigCommand runs `term2 config <command>` and returns the exit code.
+func runConfigCommand(paths Paths, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, configUsage)
+		return 2
+	}
+	switch args[0] {
+	case "schema":
+		os.Stdout.Write(marshalSchema())
+		return 0
+	case "validate":
+		file := paths.ConfigFile()
+		if len(args) > 1 {
+			file = args[1]
+		}
+		return validateConfigFile(file)
+	case "print-default":
+		if _, _, err := parseConfig("default", []byte(DefaultKeybinds)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Print(strings.TrimPrefix(DefaultKeybinds, "\n"))
+		return 0
+	case "migrate":
+		flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+		dryRun := flags.Bool("dry-run", false, "print the migrated config instead of writing it")
+		if err := flags.Parse(args[1:]); err != nil {
+			return 2
+		}
+		file := paths.ConfigFile()
+		if flags.NArg() > 0 {
+			file = flags.Arg(0)
+		}
+		return migrateConfigFile(file, *dryRun)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], configUsage)
+		return 2
+	}
+}
+
+func validateConfigFile(file string) int {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if _, _, err := parseConfig(file, data); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("%s: ok\n", file)
+	return 0
+}
+
+func migrateConfigFile(file string, dryRun bool) int {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	root, err := parseJSONC(&jsonSource{file: file, data: data})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if root.kind != jsonObject {
+		fmt.Fprintln(os.Stderr, root.errorf("$", "expected an object, got %s", jsonKindNames[root.kind]))
+		return 1
+	}
+	migrated, changes, err := migrateConfig(root)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(changes) == 0 {
+		fmt.Printf("%s is already version %d\n", file, configVersion)
+		return 0
+	}
+
+	output := formatJSON(migrated)
+	for _, change := range changes {
+		fmt.Fprintln(os.Stderr, "- "+change)
+	}
+	if dryRun {
+		os.Stdout.Write(output)
+		return 0
+	}
+	backup := file + ".bak"
+	if err := os.WriteFile(backup, data, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := os.WriteFile(file, output, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("migrated %s to version %d, comments are not kept, the old file is %s\n", file, configVersion, backup)
+
+	if _, _, err := parseConfig(file, output); err != nil {
+		fmt.Fprintln(os.Stderr, "the migrated config still has errors:")
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
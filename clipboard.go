@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// decoded size, the OSC parser already caps the encoded payload
+const maxClipboardWrite = 512 << 10
+
+const (
+	clipboardDeny       = "deny"
+	clipboardWrite      = "write"
+	clipboardReadPrompt = "read-prompt"
+	clipboardAll        = "all"
+)
+
+// ClipboardAudit records every OSC 52 request and what was done with it.
+type ClipboardAudit struct {
+	path  string
+	mutex sync.Mutex
+}
+
+func (c *ClipboardAudit) Log(t *Terminal, op string, size int, result string) {
+	line := fmt.Sprintf("%s terminal=%d profile=%q op=%s bytes=%d result=%s\n",
+		time.Now().Format(time.RFC3339), t.id, t.config.Profile, op, size, result)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		logger.Println(err)
+		return
+	}
+	file, err := os.OpenFile(c.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		logger.Println(err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.WriteString(line); err != nil {
+		logger.Println(err)
+	}
+}
+
+func (t *Terminal) clipboardPolicy() string {
+	if t.config.Clipboard != "" {
+		return t.config.Clipboard
+	}
+	return clipboardWrite
+}
+
+// handleClipboardOsc handles `OSC 52 ; selection ; base64` writes and
+// `OSC 52 ; selection ; ?` reads.
+func (t *Terminal) handleClipboardOsc(payload []byte) {
+	selection, data, ok := bytes.Cut(payload, []byte{';'})
+	if !ok {
+		return
+	}
+	if len(selection) == 0 {
+		selection = []byte("c")
+	}
+	audit := t.ctx.Value(ClipboardAuditKey).(*ClipboardAudit)
+	// the selection is echoed back in the reply, anything else could inject input
+	if bytes.ContainsFunc(selection, func(r rune) bool { return !strings.ContainsRune("cpqs01234567", r) }) {
+		audit.Log(t, "unknown", 0, "denied (invalid selection)")
+		return
+	}
+	selection = bytes.Clone(selection)
+	policy := t.clipboardPolicy()
+
+	if string(data) == "?" {
+		switch policy {
+		case clipboardAll:
+			audit.Log(t, "read", 0, "allowed")
+			t.replyClipboard(selection)
+		case clipboardReadPrompt:
+			t.mutex.Lock()
+			pending := t.clipboardPrompt
+			t.clipboardPrompt = true
+			t.mutex.Unlock()
+			if pending {
+				audit.Log(t, "read", 0, "denied (prompt open)")
+				return
+			}
+			// the dialog blocks, the read thread has to keep going
+			go t.promptClipboardRead(selection)
+		default:
+			audit.Log(t, "read", 0, "denied")
+		}
+		return
+	}
+
+	if policy == clipboardDeny {
+		audit.Log(t, "write", base64.StdEncoding.DecodedLen(len(data)), "denied")
+		return
+	}
+	if base64.StdEncoding.DecodedLen(len(data)) > maxClipboardWrite {
+		audit.Log(t, "write", base64.StdEncoding.DecodedLen(len(data)), "denied (too large)")
+		return
+	}
+	text, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		audit.Log(t, "write", 0, "denied (invalid base64)")
+		return
+	}
+	if err := runtime.ClipboardSetText(t.ctx, string(text)); err != nil {
+		logger.Println(err)
+		audit.Log(t, "write", len(text), "failed")
+		return
+	}
+	audit.Log(t, "write", len(text), "allowed")
+}
+
+func (t *Terminal) promptClipboardRead(selection []byte) {
+	defer func() {
+		t.mutex.Lock()
+		t.clipboardPrompt = false
+		t.mutex.Unlock()
+	}()
+	audit := t.ctx.Value(ClipboardAuditKey).(*ClipboardAudit)
+
+	name := t.config.Profile
+	if name == "" {
+		name = fmt.Sprintf("Terminal %d", t.id)
+	}
+	result, err := runtime.MessageDialog(t.ctx, runtime.MessageDialogOptions{
+		Type:          runtime.QuestionDialog,
+		Title:         "Clipboard access",
+		Message:       name + " wants to read your clipboard. Allow it?",
+		Buttons:       []string{"Yes", "No"},
+		DefaultButton: "No",
+		CancelButton:  "No",
+	})
+	if err != nil {
+		logger.Println(err)
+		audit.Log(t, "read", 0, "failed")
+		return
+	}
+	if result != "Yes" {
+		audit.Log(t, "read", 0, "denied (prompt)")
+		return
+	}
+	audit.Log(t, "read", 0, "allowed (prompt)")
+	t.replyClipboard(selection)
+}
+
+func (t *Terminal) replyClipboard(selection []byte) {
+	text, err := runtime.ClipboardGetText(t.ctx)
+	if err != nil {
+		logger.Println(err)
+	}
+	reply := fmt.Sprintf("\x1b]52;%s;%s\x07", selection, base64.StdEncoding.EncodeToString([]byte(text)))
+	if err := t.send([]byte(reply)); err != nil {
+		logger.Println(err)
+	}
+}
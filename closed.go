@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const defaultClosedLimit = 10
+
+// ClosedTerminals controls the list reopenClosedTerminal takes tabs from.
+type ClosedTerminals struct {
+	Limit   int  `json:"limit,omitempty"`   // how many are kept, 0 keeps none
+	Persist bool `json:"persist,omitempty"` // keep them across restarts, gzipped in the state directory
+}
+
+func (c *ClosedTerminals) setDefaults() {
+	c.Limit = defaultClosedLimit
+}
+
+// ClosedTerminal is a tab that was closed, its output is in a file next to
+// the list when they are persisted.
+type ClosedTerminal struct {
+	WorkspaceTerminal
+	ShownTitle string    `json:"shownTitle"`
+	Scrollback string    `json:"scrollback"` // file in the closed directory
+	Closed     time.Time `json:"closed"`
+
+	output []byte // nil until read back for entries of an earlier run
+}
+
+// closedTerminals is the list of recently closed tabs, the last one was
+// closed last. Files are written by writeClosed, not on the way out of a
+// terminal.
+type closedTerminals struct {
+	mutex   sync.Mutex
+	entries []ClosedTerminal
+	wake    chan struct{}
+}
+
+func (c *closedTerminals) changed() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (a *App) closedDir() string {
+	return filepath.Join(a.paths.State, "closed")
+}
+
+func (a *App) closedFile() string {
+	return filepath.Join(a.closedDir(), "closed.json")
+}
+
+func (a *App) closedSettings() ClosedTerminals {
+	a.configMutex.Lock()
+	defer a.configMutex.Unlock()
+	if a.config == nil || a.config.ClosedTerminals == nil {
+		settings := ClosedTerminals{}
+		settings.setDefaults()
+		return settings
+	}
+	return *a.config.ClosedTerminals
+}
+
+// loadClosed reads the tabs closed in earlier runs, the files only exist
+// when they are persisted. Their output is read when they are reopened.
+func (a *App) loadClosed() *closedTerminals {
+	closed := &closedTerminals{wake: make(chan struct{}, 1)}
+	go a.writeClosed(closed)
+	data, err := os.ReadFile(a.closedFile())
+	if errors.Is(err, fs.ErrNotExist) {
+		return closed
+	} else if err != nil {
+		logger.Println(err)
+		return closed
+	}
+	if err := json.Unmarshal(data, &closed.entries); err != nil {
+		logger.Printf("%s: %v\n", a.closedFile(), err)
+	}
+	return closed
+}
+
+// writeClosed keeps the closed directory in line with the list, the output
+// of an entry is written once and dropped with it.
+func (a *App) writeClosed(closed *closedTerminals) {
+	for range closed.wake {
+		settings := a.closedSettings()
+		closed.mutex.Lock()
+		entries := make([]ClosedTerminal, len(closed.entries))
+		copy(entries, closed.entries)
+		closed.mutex.Unlock()
+
+		if !settings.Persist || len(entries) == 0 {
+			if err := os.RemoveAll(a.closedDir()); err != nil {
+				logger.Println(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(a.closedDir(), 0700); err != nil {
+			logger.Println(err)
+			continue
+		}
+		keep := map[string]bool{filepath.Base(a.closedFile()): true}
+		for _, entry := range entries {
+			keep[entry.Scrollback] = true
+			file := filepath.Join(a.closedDir(), entry.Scrollback)
+			if _, err := os.Stat(file); err == nil || entry.output == nil {
+				continue
+			}
+			if err := writeSnapshot(file, entry.output); err != nil {
+				logger.Println(err)
+			}
+		}
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			logger.Println(err)
+			continue
+		}
+		if err := writeFileAtomic(a.closedFile(), append(data, '\n'), 0600); err != nil {
+			logger.Println(err)
+		}
+		files, _ := os.ReadDir(a.closedDir())
+		for _, file := range files {
+			if !keep[file.Name()] {
+				os.Remove(filepath.Join(a.closedDir(), file.Name()))
+			}
+		}
+	}
+}
+
+// recordClosed remembers a terminal that is going away, before it leaves the
+// registry so its directory can still be looked up.
+func (a *App) recordClosed(term *Terminal) {
+	settings := a.closedSettings()
+	closed := a.ctx.Value(ClosedKey).(*closedTerminals)
+	defer closed.changed()
+	if settings.Limit <= 0 {
+		closed.mutex.Lock()
+		closed.entries = nil
+		closed.mutex.Unlock()
+		return
+	}
+
+	entry := ClosedTerminal{
+		WorkspaceTerminal: a.workspaceTerminal(term),
+		ShownTitle:        term.displayTitle(),
+		Closed:            time.Now(),
+	}
+	// a fresh shell of the profile, nothing is run again
+	entry.Input = ""
+	entry.Command = nil
+	entry.Scrollback = fmt.Sprintf("%d.gz", entry.Closed.UnixNano())
+	term.mutex.Lock()
+	entry.output, _ = term.scrollback.Slice(0, term.scrollback.End())
+	term.mutex.Unlock()
+
+	closed.mutex.Lock()
+	defer closed.mutex.Unlock()
+	closed.entries = append(closed.entries, entry)
+	if over := len(closed.entries) - settings.Limit; over > 0 {
+		closed.entries = append([]ClosedTerminal(nil), closed.entries[over:]...)
+	}
+}
+
+// ReopenClosedTerminal takes the last closed terminal off the list, nil when
+// there is none. The tab comes back with its output and a new shell of the
+// same profile in the same directory.
+func (a *App) ReopenClosedTerminal() (*TerminalRequest, error) {
+	closed := a.ctx.Value(ClosedKey).(*closedTerminals)
+	closed.mutex.Lock()
+	if len(closed.entries) == 0 {
+		closed.mutex.Unlock()
+		return nil, nil
+	}
+	entry := closed.entries[len(closed.entries)-1]
+	output := entry.output
+	if output == nil {
+		// read before the entry is gone and the writer removes the file
+		var err error
+		output, err = readSnapshot(filepath.Join(a.closedDir(), filepath.Base(entry.Scrollback)))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			logger.Println(err)
+		}
+	}
+	closed.entries = closed.entries[:len(closed.entries)-1]
+	closed.mutex.Unlock()
+	closed.changed()
+
+	workspace := Workspace{Terminals: []WorkspaceTerminal{entry.WorkspaceTerminal}}
+	request := workspace.requests(a.closedFile())[0]
+	request.Scrollback = output
+	return &request, nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const maxCommandRecords = 1000
+
+type CommandRecord struct {
+	ID          int        `json:"id"`
+	Command     string     `json:"command"`
+	Cwd         string     `json:"cwd"`
+	Start       time.Time  `json:"start"`
+	End         *time.Time `json:"end"`
+	ExitCode    *int       `json:"exitCode"`
+	OutputStart int64      `json:"outputStart"`
+	OutputEnd   int64      `json:"outputEnd"`
+}
+
+// commandTracker turns the OSC 133 marks of a terminal into command records.
+type commandTracker struct {
+	records   []CommandRecord
+	current   *CommandRecord
+	nextID    int
+	promptEnd int64
+	marked    bool
+}
+
+func newCommandTracker() *commandTracker {
+	return &commandTracker{promptEnd: -1}
+}
+
+// handleCommandMark is called from the read thread for every OSC 133 sequence.
+func (t *Terminal) handleCommandMark(payload []byte) {
+	kind, params, _ := bytes.Cut(payload, []byte{';'})
+	now := time.Now()
+
+	t.mutex.Lock()
+	commands := t.commands
+	commands.marked = true
+	var event *CommandRecord
+	switch string(kind) {
+	case "A": // prompt start
+		select {
+		case <-t.prompted:
+		default:
+			close(t.prompted)
+		}
+		if commands.current != nil {
+			// the shell came back without reporting an exit status
+			event = commands.finish(now, nil, t.osc.SequenceStart())
+		}
+		commands.promptEnd = -1
+	case "B": // prompt end
+		commands.promptEnd = t.osc.Offset()
+	case "C": // command output start
+		if commands.current != nil {
+			commands.finish(now, nil, t.osc.SequenceStart())
+		}
+		command, ok := commandLine(params)
+		if !ok && commands.promptEnd != -1 {
+			input, _ := t.scrollback.Slice(commands.promptEnd, t.osc.SequenceStart())
+			command = strings.TrimSpace(stripAnsi(input))
+		}
+		commands.current = &CommandRecord{
+			ID:          commands.nextID,
+			Command:     command,
+			Cwd:         t.cwd,
+			Start:       now,
+			OutputStart: t.osc.Offset(),
+		}
+		commands.nextID++
+		commands.promptEnd = -1
+		record := *commands.current
+		event = &record
+	case "D": // command finished
+		if commands.current == nil {
+			break
+		}
+		var exitCode *int
+		if code, err := strconv.Atoi(string(bytes.TrimSpace(bytes.SplitN(params, []byte{';'}, 2)[0]))); err == nil {
+			exitCode = &code
+		}
+		event = commands.finish(now, exitCode, t.osc.SequenceStart())
+	}
+	t.mutex.Unlock()
+
+	if event != nil {
+		runtime.EventsEmit(t.ctx, "terminal:command", t.id, *event)
+		if event.End != nil {
+			t.commandFinished(*event)
+			t.notifyCommandFinished(*event)
+			// programs that crash mid progress never clear it themselves
+			t.setProgress(Progress{State: "none"})
+		}
+	}
+}
+
+func (t *Terminal) commandFinished(record CommandRecord) {
+	if record.Command == "" {
+		return
+	}
+	t.ctx.Value(HistoryKey).(*History).Add(HistoryEntry{
+		Command:  record.Command,
+		Profile:  t.config.Profile,
+		Cwd:      record.Cwd,
+		ExitCode: record.ExitCode,
+		Start:    record.Start,
+		Duration: record.End.Sub(record.Start).Milliseconds(),
+	})
+}
+
+func (c *commandTracker) finish(end time.Time, exitCode *int, outputEnd int64) *CommandRecord {
+	record := *c.current
+	record.End = &end
+	record.ExitCode = exitCode
+	record.OutputEnd = outputEnd
+	c.current = nil
+
+	c.records = append(c.records, record)
+	if len(c.records) > maxCommandRecords {
+		c.records = append(c.records[:0:0], c.records[len(c.records)-maxCommandRecords:]...)
+	}
+	return &record
+}
+
+// commandLine reads the command text some integrations attach to the C mark.
+func commandLine(params []byte) (string, bool) {
+	for _, param := range bytes.Split(params, []byte{';'}) {
+		key, value, _ := bytes.Cut(param, []byte{'='})
+		switch string(key) {
+		case "cmdline_url":
+			if command, err := url.PathUnescape(string(value)); err == nil {
+				return command, true
+			}
+		case "cmdline":
+			return string(value), true
+		}
+	}
+	return "", false
+}
+
+func (a *App) GetCommands(id int) ([]CommandRecord, error) {
+	term, err := a.terminal(id)
+	if err != nil {
+		return nil, err
+	}
+	term.mutex.Lock()
+	defer term.mutex.Unlock()
+	records := append([]CommandRecord{}, term.commands.records...)
+	if term.commands.current != nil {
+		records = append(records, *term.commands.current)
+	}
+	return records, nil
+}
+
+// GetCommandOutput returns the plain text output of a command, a negative
+// commandId picks the last finished command.
+func (a *App) GetCommandOutput(id int, commandId int) (string, error) {
+	term, err := a.terminal(id)
+	if err != nil {
+		return "", err
+	}
+	term.mutex.Lock()
+	defer term.mutex.Unlock()
+
+	var record *CommandRecord
+	records := term.commands.records
+	if commandId < 0 {
+		if len(records) == 0 {
+			return "", fmt.Errorf("no finished commands in terminal %d", id)
+		}
+		record = &records[len(records)-1]
+	} else if term.commands.current != nil && term.commands.current.ID == commandId {
+		record = term.commands.current
+	} else {
+		for i := range records {
+			if records[i].ID == commandId {
+				record = &records[i]
+				break
+			}
+		}
+	}
+	if record == nil {
+		return "", fmt.Errorf("command %d not found in terminal %d", commandId, id)
+	}
+
+	end := record.OutputEnd
+	if record.End == nil {
+		end = term.scrollback.End()
+	}
+	output, complete := term.scrollback.Slice(record.OutputStart, end)
+	if !complete {
+		logger.Printf("Output of command %d in terminal %d is truncated\n", commandId, id)
+	}
+	return strings.Trim(stripAnsi(output), "\n"), nil
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Config is the parsed config file, defaults are filled in so the frontend
+// gets the same shape whatever the user left out.
+type Config struct {
+	Version         int               `json:"version,omitempty"`
+	Fonts           []Font            `json:"fonts"`
+	DefaultProfile  string            `json:"defaultProfile"`
+	Profiles        []Profile         `json:"profiles"`
+	DefaultScope    string            `json:"defaultScope"`
+	Shortcuts       []ShortcutBinding `json:"shortcuts"`
+	LoginShellEnv   *LoginShellEnv    `json:"loginShellEnv,omitempty"`
+	Automation      *Automation       `json:"automation,omitempty"`
+	Session         *SessionSettings  `json:"session,omitempty"`
+	ClosedTerminals *ClosedTerminals  `json:"closedTerminals,omitempty"`
+}
+
+type Font struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type Profile struct {
+	Name                string               `json:"name"`
+	Command             string               `json:"command"`
+	Args                []string             `json:"args"`
+	Cwd                 *string              `json:"cwd,omitempty"`
+	InheritCwd          bool                 `json:"inheritCwd,omitempty"`
+	ShellIntegration    *bool                `json:"shellIntegration,omitempty"`
+	CommandNotification *CommandNotification `json:"commandNotification,omitempty"`
+	Notifications       *NotificationPolicy  `json:"notifications,omitempty"`
+	Clipboard           string               `json:"clipboard,omitempty"`
+	TitleTemplate       string               `json:"titleTemplate,omitempty"`
+	Env                 map[string]*string   `json:"env,omitempty"`
+	EnvFiles            []string             `json:"envFiles,omitempty"`
+	PathPrepend         []string             `json:"pathPrepend,omitempty"`
+	PathAppend          []string             `json:"pathAppend,omitempty"`
+	StartupInput        string               `json:"startupInput,omitempty"`
+	StartupPattern      string               `json:"startupPattern,omitempty"`
+	PreLaunch           []string             `json:"preLaunch,omitempty"`
+	PostExit            []string             `json:"postExit,omitempty"`
+	Font                string               `json:"font"`
+	FontSize            float64              `json:"fontSize"`
+	Logo                string               `json:"logo"`
+	BackgroundImage     string               `json:"backgroundImage"`
+	Theme               Theme                `json:"theme"`
+	Shortcut            Shortcut             `json:"shortcut"`
+}
+
+type Theme struct {
+	Background                  string `json:"background"`
+	SelectionBackground         string `json:"selectionBackground"`
+	SelectionInactiveBackground string `json:"selectionInactiveBackground"`
+}
+
+type Shortcut struct {
+	Code     string `json:"code"`
+	Type     string `json:"type,omitempty"` // keydown (default) or keyup
+	CtrlKey  bool   `json:"ctrlKey,omitempty"`
+	ShiftKey bool   `json:"shiftKey,omitempty"`
+	AltKey   bool   `json:"altKey,omitempty"`
+}
+
+type ShortcutBinding struct {
+	Shortcut  Shortcut `json:"shortcut"`
+	Action    string   `json:"action"`
+	Scopes    []string `json:"scopes,omitempty"`
+	SetScope  string   `json:"setScope,omitempty"`
+	Workspace string   `json:"workspace,omitempty"` // for openWorkspace and saveWorkspace, a name or a path
+}
+
+// configDefaults is implemented by config types with defaults, the decoder
+// calls it before filling in what the file has.
+type configDefaults interface {
+	setDefaults()
+}
+
+func (p *Profile) setDefaults() {
+	shellIntegration := true
+	p.ShellIntegration = &shellIntegration
+	p.Clipboard = clipboardWrite
+	p.TitleTemplate = defaultTitleTemplate
+}
+
+func (s *Shortcut) setDefaults() {
+	s.Type = "keydown"
+}
+
+func (b *ShortcutBinding) setDefaults() {
+	b.Scopes = []string{"default"}
+}
+
+func (c *CommandNotification) setDefaults() {
+	c.Threshold = defaultCommandThreshold
+}
+
+func (n *NotificationPolicy) setDefaults() {
+	n.Osc = policyToast
+	n.Bell = policyIgnore
+}
+
+// configActions are the actions the frontend implements.
+var configActions = []string{
+	"newTerminal",
+	"newTerminalHere",
+	"closeTerminal",
+	"nextTab",
+	"previousTab",
+	"closeTabSwitcher",
+	"toggleTerminalMode",
+	"copy",
+	"paste",
+	"previousPrompt",
+	"nextPrompt",
+	"copyLastCommandOutput",
+	"openConfigFile",
+	"openWorkspace",
+	"saveWorkspace",
+	"reopenClosedTerminal",
+}
+
+// shortcutCodes are the KeyboardEvent.code values shortcuts can use.
+var shortcutCodes = []string{
+	"KeyA", "KeyB", "KeyC", "KeyD", "KeyE", "KeyF", "KeyG", "KeyH", "KeyI",
+	"KeyJ", "KeyK", "KeyL", "KeyM", "KeyN", "KeyO", "KeyP", "KeyQ", "KeyR",
+	"KeyS", "KeyT", "KeyU", "KeyV", "KeyW", "KeyX", "KeyY", "KeyZ",
+	"Digit1", "Digit2", "Digit3", "Digit4", "Digit5", "Digit6", "Digit7",
+	"Digit8", "Digit9", "Digit0",
+	"Enter", "Escape", "Backspace", "Tab", "Space", "Minus", "Equal",
+	"BracketLeft", "BracketRight", "Backslash", "Semicolon", "Quote",
+	"Backquote", "Comma", "Period", "Slash", "CapsLock",
+	"F1", "F2", "F3", "F4", "F5", "F6", "F7", "F8", "F9", "F10", "F11", "F12",
+	"PrintScreen", "ScrollLock", "Pause", "Insert", "Home", "PageUp",
+	"Delete", "End", "PageDown", "ArrowRight", "ArrowLeft", "ArrowDown",
+	"ArrowUp",
+	"NumLock", "NumpadDivide", "NumpadMultiply", "NumpadSubtract",
+	"NumpadAdd", "NumpadEnter", "Numpad1", "Numpad2", "Numpad3", "Numpad4",
+	"Numpad5", "Numpad6", "Numpad7", "Numpad8", "Numpad9", "Numpad0",
+	"NumpadDecimal",
+	"Control", "ControlLeft", "ControlRight", "Shift", "ShiftLeft",
+	"ShiftRight", "Alt", "AltLeft", "AltRight",
+}
+
+var (
+	clipboardPolicies    = []string{clipboardDeny, clipboardWrite, clipboardReadPrompt, clipboardAll}
+	notificationPolicies = []string{policyIgnore, policyToast, policyDesktop, policyAttention}
+)
+
+// ConfigError points at the place in a config file that is wrong.
+type ConfigError struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e *ConfigError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", e.File, e.Line, e.Column, e.Path, e.Message)
+}
+
+type ConfigErrors []*ConfigError
+
+func (e ConfigErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// parseConfig reads a JSONC config and everything it includes, file is
+// where data came from. The files that were read are returned even when the
+// config is invalid so they can be watched.
+func parseConfig(file string, data []byte) (*Config, []string, error) {
+	loader := &configLoader{}
+	root := loader.load(file, data, jsonPos{})
+	if root != nil {
+		root = loader.resolveProfiles(root)
+	}
+	if len(loader.errors) > 0 {
+		return nil, loader.files, loader.errors
+	}
+
+	if _, err := configFileVersion(root); err != nil {
+		return nil, loader.files, ConfigErrors{err.(*ConfigError)}
+	}
+
+	config := &Config{}
+	decoder := newJSONDecoder()
+	decoder.decode(root, reflect.ValueOf(config).Elem(), "$")
+	if len(decoder.errors) > 0 {
+		if version, _ := configFileVersion(root); version < configVersion {
+			decoder.errorAt(root.jsonPos, "$.version", "this config is version %d, `term2 config migrate` upgrades it to version %d", version, configVersion)
+		}
+		return nil, loader.files, decoder.errors
+	}
+	validateConfig(config, decoder)
+	if len(decoder.errors) > 0 {
+		return nil, loader.files, decoder.errors
+	}
+	return config, loader.files, nil
+}
+
+func validateConfig(config *Config, d *jsonDecoder) {
+	validateShortcut := func(shortcut Shortcut, path string) {
+		if !slices.Contains(shortcutCodes, shortcut.Code) {
+			d.errorPath(path+".code", "unknown key code %q", shortcut.Code)
+		}
+		if shortcut.Type != "keydown" && shortcut.Type != "keyup" {
+			d.errorPath(path+".type", "must be keydown or keyup")
+		}
+	}
+	oneOf := func(value string, allowed []string, path string) {
+		if !slices.Contains(allowed, value) {
+			d.errorPath(path, "must be one of %s", strings.Join(allowed, ", "))
+		}
+	}
+	expandable := func(value string, path string) {
+		if _, err := syntaxExpander.expand(value); err != nil {
+			d.errorPath(path, "%v", err)
+		}
+	}
+
+	names := make(map[string]bool)
+	for i, profile := range config.Profiles {
+		path := fmt.Sprintf("$.profiles[%d]", i)
+		if profile.Name == "" {
+			d.errorPath(path+".name", "must not be empty")
+		} else if names[profile.Name] {
+			d.errorPath(path+".name", "duplicate profile name %q", profile.Name)
+		}
+		names[profile.Name] = true
+		if profile.Command == "" {
+			d.errorPath(path+".command", "must not be empty")
+		}
+		expandable(profile.Command, path+".command")
+		for j, arg := range profile.Args {
+			expandable(arg, fmt.Sprintf("%s.args[%d]", path, j))
+		}
+		if profile.Cwd != nil {
+			expandable(*profile.Cwd, path+".cwd")
+		}
+		for _, key := range sortedKeys(profile.Env) {
+			if !validName(key) {
+				d.errorPath(path+".env."+key, "invalid variable name %q", key)
+			} else if value := profile.Env[key]; value != nil {
+				expandable(*value, path+".env."+key)
+			}
+		}
+		for j, file := range profile.EnvFiles {
+			expandable(file, fmt.Sprintf("%s.envFiles[%d]", path, j))
+		}
+		for j, dir := range profile.PathPrepend {
+			expandable(dir, fmt.Sprintf("%s.pathPrepend[%d]", path, j))
+		}
+		for j, dir := range profile.PathAppend {
+			expandable(dir, fmt.Sprintf("%s.pathAppend[%d]", path, j))
+		}
+		if profile.StartupPattern != "" {
+			if _, err := regexp.Compile(profile.StartupPattern); err != nil {
+				d.errorPath(path+".startupPattern", "%v", err)
+			}
+		}
+		hook := func(command []string, path string) {
+			if len(command) > 0 && command[0] == "" {
+				d.errorPath(path+"[0]", "must not be empty")
+			}
+			for j, arg := range command {
+				expandable(arg, fmt.Sprintf("%s[%d]", path, j))
+			}
+		}
+		hook(profile.PreLaunch, path+".preLaunch")
+		hook(profile.PostExit, path+".postExit")
+		if profile.FontSize <= 0 {
+			d.errorPath(path+".fontSize", "must be greater than 0")
+		}
+		oneOf(profile.Clipboard, clipboardPolicies, path+".clipboard")
+		if profile.Notifications != nil {
+			oneOf(profile.Notifications.Osc, notificationPolicies, path+".notifications.osc")
+			oneOf(profile.Notifications.Bell, notificationPolicies, path+".notifications.bell")
+		}
+		validateShortcut(profile.Shortcut, path+".shortcut")
+	}
+	if !names[config.DefaultProfile] {
+		d.errorPath("$.defaultProfile", "no profile named %q", config.DefaultProfile)
+	}
+
+	if config.LoginShellEnv != nil && config.LoginShellEnv.Timeout <= 0 {
+		d.errorPath("$.loginShellEnv.timeout", "must be greater than 0")
+	}
+	if config.Session != nil {
+		oneOf(config.Session.Restore, restorePolicies, "$.session.restore")
+	}
+	if config.ClosedTerminals != nil && config.ClosedTerminals.Limit < 0 {
+		d.errorPath("$.closedTerminals.limit", "must not be negative")
+	}
+
+	for i, binding := range config.Shortcuts {
+		path := fmt.Sprintf("$.shortcuts[%d]", i)
+		validateShortcut(binding.Shortcut, path+".shortcut")
+		if !slices.Contains(configActions, binding.Action) {
+			d.errorPath(path+".action", "unknown action %q", binding.Action)
+		}
+		if binding.Workspace != "" && binding.Action != "openWorkspace" && binding.Action != "saveWorkspace" {
+			d.errorPath(path+".workspace", "only openWorkspace and saveWorkspace take a workspace")
+		}
+	}
+}
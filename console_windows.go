@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+var procAttachConsole = windows.NewLazySystemDLL("kernel32.dll").NewProc("AttachConsole")
+
+const attachParentProcess = ^uint32(0)
+
+// attachConsole lets the command line tools print to the console they were
+// started from, term2 is built as a GUI program that doesn't get one.
+// Output that is already redirected is left alone.
+func attachConsole() {
+	redirected := func(std uint32) bool {
+		handle, err := windows.GetStdHandle(std)
+		return err == nil && handle != 0 && handle != windows.InvalidHandle
+	}
+	stdout, stderr := redirected(windows.STD_OUTPUT_HANDLE), redirected(windows.STD_ERROR_HANDLE)
+	if stdout && stderr {
+		return
+	}
+	if ok, _, _ := procAttachConsole.Call(uintptr(attachParentProcess)); ok == 0 {
+		return
+	}
+	console, err := windows.CreateFile(windows.StringToUTF16Ptr("CONOUT$"), windows.GENERIC_READ|windows.GENERIC_WRITE, windows.FILE_SHARE_WRITE, nil, windows.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return
+	}
+	if !stdout {
+		os.Stdout = os.NewFile(uintptr(console), "/dev/stdout")
+	}
+	if !stderr {
+		os.Stderr = os.NewFile(uintptr(console), "/dev/stderr")
+	}
+}
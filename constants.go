@@ -3,6 +3,8 @@ package main
 const (
 	DefaultKeybinds string = `
 {
+  "$schema": "./config.schema.json",
+  "version": 2,
   "fonts": [
     {
       "name": "CaskaydiaCove NF Mono Regular",
@@ -12,7 +14,23 @@ const (
   "defaultProfile": "PowerShell",
   "profiles": [
     {
-      "name": "create your own profile here",
+      // create your own profiles here
+      "name": "PowerShell",
+      "command": "powershell.exe",
+      "args": ["-NoLogo"],
+      "font": "CaskaydiaCove NF Mono Regular",
+      "fontSize": 18,
+      "logo": "./powershell.svg",
+      "backgroundImage": "./background.jpeg",
+      "theme": {
+        "background": "rgba(0, 0, 0, 0)",
+        "selectionBackground": "#FFFFFF99",
+        "selectionInactiveBackground": "#FFFFFF99"
+      },
+      "shortcut": {
+        "code": "Digit1",
+        "ctrlKey": true
+      }
     }
   ],
   "defaultScope": "default",
@@ -47,11 +65,21 @@ const (
       "scopes": ["default"],
       "action": "newTerminal"
     },
+    {
+      "shortcut": { "code": "KeyN", "ctrlKey": true, "shiftKey": true },
+      "scopes": ["default"],
+      "action": "newTerminalHere"
+    },
     {
       "shortcut": { "code": "KeyW", "ctrlKey": true },
       "scopes": ["default"],
       "action": "closeTerminal"
     },
+    {
+      "shortcut": { "code": "KeyT", "ctrlKey": true, "shiftKey": true },
+      "scopes": ["default"],
+      "action": "reopenClosedTerminal"
+    },
     {
       "shortcut": { "code": "KeyC", "ctrlKey": true },
       "scopes": ["default"],
@@ -67,6 +95,21 @@ const (
       "scopes": ["default"],
       "action": "toggleTerminalMode"
     },
+    {
+      "shortcut": { "code": "ArrowUp", "ctrlKey": true, "shiftKey": true },
+      "scopes": ["default"],
+      "action": "previousPrompt"
+    },
+    {
+      "shortcut": { "code": "ArrowDown", "ctrlKey": true, "shiftKey": true },
+      "scopes": ["default"],
+      "action": "nextPrompt"
+    },
+    {
+      "shortcut": { "code": "KeyC", "ctrlKey": true, "shiftKey": true },
+      "scopes": ["default"],
+      "action": "copyLastCommandOutput"
+    },
     {
       "shortcut": {
         "code": "Comma",
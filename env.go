@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// envNameEqual compares variable names the way the OS does.
+func envNameEqual(a string, b string) bool {
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// setEnv replaces every entry of key in env.
+func setEnv(env []string, key string, value string) []string {
+	return append(unsetEnv(env, key), key+"="+value)
+}
+
+func unsetEnv(env []string, key string) []string {
+	return slices.DeleteFunc(env, func(entry string) bool {
+		name, _, _ := strings.Cut(entry, "=")
+		return envNameEqual(name, key)
+	})
+}
+
+// buildEnv builds the environment of a terminal on top of base, in order:
+// the env files, env and the PATH changes. Values can reference variables
+// from base and the env files.
+func (a *App) buildEnv(base []string, config TerminalConfig) ([]string, error) {
+	env := slices.Clone(base)
+	lookup := func(name string) (string, bool) {
+		return lookupEnv(env, name)
+	}
+	expander := newExpander(config.Profile, a.paths.Config, lookup)
+
+	for i, file := range config.EnvFiles {
+		path, err := expander.expand(file)
+		if err != nil {
+			return nil, fmt.Errorf("envFiles[%d]: %w", i, err)
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(a.paths.Config, path)
+		}
+		vars, err := readEnvFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("envFiles[%d]: %w", i, err)
+		}
+		for _, v := range vars {
+			value := v.value
+			if v.expand {
+				if value, err = expander.expand(value); err != nil {
+					return nil, fmt.Errorf("envFiles[%d]: %s:%d: %s: %w", i, path, v.line, v.key, err)
+				}
+			}
+			env = setEnv(env, v.key, value)
+		}
+	}
+
+	// values are expanded before anything is set so the order of the keys
+	// doesn't matter
+	values := make(map[string]string, len(config.Env))
+	for key, value := range config.Env {
+		if value == nil {
+			continue
+		}
+		expanded, err := expander.expand(*value)
+		if err != nil {
+			return nil, fmt.Errorf("env.%s: %w", key, err)
+		}
+		values[key] = expanded
+	}
+	for _, key := range sortedKeys(config.Env) {
+		if value, ok := values[key]; ok {
+			env = setEnv(env, key, value)
+		} else {
+			env = unsetEnv(env, key)
+		}
+	}
+
+	if len(config.PathPrepend) > 0 || len(config.PathAppend) > 0 {
+		var path []string
+		if current, ok := lookupEnv(env, "PATH"); ok && current != "" {
+			path = filepath.SplitList(current)
+		}
+		for i, dir := range config.PathPrepend {
+			expanded, err := expander.expand(dir)
+			if err != nil {
+				return nil, fmt.Errorf("pathPrepend[%d]: %w", i, err)
+			}
+			path = slices.Insert(path, i, expanded)
+		}
+		for i, dir := range config.PathAppend {
+			expanded, err := expander.expand(dir)
+			if err != nil {
+				return nil, fmt.Errorf("pathAppend[%d]: %w", i, err)
+			}
+			path = append(path, expanded)
+		}
+		env = setEnv(env, pathKey(env), strings.Join(path, string(os.PathListSeparator)))
+	}
+	return env, nil
+}
+
+// pathKey keeps the spelling of PATH that is already in env, Windows uses Path.
+func pathKey(env []string) string {
+	for i := len(env) - 1; i >= 0; i-- {
+		if name, _, ok := strings.Cut(env[i], "="); ok && envNameEqual(name, "PATH") {
+			return name
+		}
+	}
+	return "PATH"
+}
+
+// lookPath finds command in the PATH of env, exec.Command only searches
+// the PATH term2 itself was started with.
+func lookPath(command string, env []string) (string, bool) {
+	if strings.ContainsAny(command, `/\`) {
+		return "", false
+	}
+	path, _ := lookupEnv(env, "PATH")
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			continue
+		}
+		if found, err := exec.LookPath(filepath.Join(dir, command)); err == nil {
+			return found, true
+		}
+	}
+	return "", false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+type envFileVar struct {
+	key    string
+	value  string
+	expand bool
+	line   int
+}
+
+// readEnvFile reads a dotenv file. Lines are KEY=value with an optional
+// `export ` in front, # starts a comment. Double quoted values understand
+// \n, \t, \" and \\, single quoted values are taken as they are and
+// everything else is expanded, so a line can use the ones before it.
+func readEnvFile(path string) ([]envFileVar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	var vars []envFileVar
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimPrefix(text, "export ")
+		key, value, ok := strings.Cut(text, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !validName(key) {
+			return nil, fmt.Errorf("%s:%d: expected KEY=value", path, line)
+		}
+		v, err := parseEnvValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s: %w", path, line, key, err)
+		}
+		v.key, v.line = key, line
+		vars = append(vars, v)
+	}
+	return vars, scanner.Err()
+}
+
+func parseEnvValue(value string) (envFileVar, error) {
+	switch {
+	case strings.HasPrefix(value, "'"):
+		end := strings.IndexByte(value[1:], '\'')
+		if end == -1 {
+			return envFileVar{}, errors.New("unclosed '")
+		}
+		return envFileVar{value: value[1 : end+1]}, nil
+	case strings.HasPrefix(value, `"`):
+		var unquoted strings.Builder
+		for i := 1; i < len(value); i++ {
+			c := value[i]
+			if c == '"' {
+				return envFileVar{value: unquoted.String(), expand: true}, nil
+			}
+			if c == '\\' && i+1 < len(value) {
+				i++
+				switch value[i] {
+				case 'n':
+					c = '\n'
+				case 't':
+					c = '\t'
+				case '"', '\\':
+					c = value[i]
+				default:
+					unquoted.WriteByte('\\')
+					c = value[i]
+				}
+			}
+			unquoted.WriteByte(c)
+		}
+		return envFileVar{}, errors.New(`unclosed "`)
+	default:
+		if i := strings.Index(value, " #"); i != -1 {
+			value = strings.TrimSpace(value[:i])
+		}
+		return envFileVar{value: value, expand: true}, nil
+	}
+}
+
+// GetEnvironment returns the environment a terminal was started with.
+func (a *App) GetEnvironment(id int) ([]string, error) {
+	term, err := a.terminal(id)
+	if err != nil {
+		return nil, err
+	}
+	env := slices.Clone(term.cmd.Env)
+	slices.Sort(env)
+	return env, nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// expander replaces references in profile fields:
+//   - `~` at the start, followed by nothing or a separator, is the home directory
+//   - `${VAR}` is an environment variable, `${env:VAR}` only looks at the
+//     environment even when VAR is a built-in
+//   - `${VAR:-default}` uses default when VAR is unset or empty, the default
+//     is expanded too
+//   - `${configDir}` and `${profileName}` are built-ins
+//   - `$${` is a literal `${`
+//
+// Anything else, like a bare `$VAR`, is left for the shell, PowerShell's
+// `$env:PATH` is common in args.
+type expander struct {
+	lookup   func(name string) (string, bool)
+	builtins map[string]string
+	home     func() (string, error)
+}
+
+func newExpander(profile string, configDir string, lookup func(string) (string, bool)) *expander {
+	return &expander{
+		lookup: lookup,
+		builtins: map[string]string{
+			"configDir":   configDir,
+			"profileName": profile,
+		},
+		home: os.UserHomeDir,
+	}
+}
+
+// syntaxExpander treats every variable as set and empty, it is used to check
+// the config without depending on the environment it is loaded in.
+var syntaxExpander = &expander{
+	lookup:   func(string) (string, bool) { return "", true },
+	builtins: map[string]string{"configDir": "", "profileName": ""},
+	home:     func() (string, error) { return "", nil },
+}
+
+func (e *expander) expand(s string) (string, error) {
+	var result strings.Builder
+	if s == "~" || strings.HasPrefix(s, "~/") || strings.HasPrefix(s, `~\`) {
+		home, err := e.home()
+		if err != nil {
+			return "", fmt.Errorf("couldn't expand ~: %w", err)
+		}
+		result.WriteString(home)
+		s = s[1:]
+	}
+
+	for {
+		i := strings.IndexByte(s, '$')
+		if i == -1 {
+			result.WriteString(s)
+			return result.String(), nil
+		}
+		result.WriteString(s[:i])
+		s = s[i+1:]
+
+		switch {
+		case strings.HasPrefix(s, "${"):
+			result.WriteString("${")
+			s = s[2:]
+		case strings.HasPrefix(s, "{"):
+			end := closingBrace(s)
+			if end == -1 {
+				return "", errors.New("unclosed ${")
+			}
+			value, err := e.reference(s[1:end])
+			if err != nil {
+				return "", err
+			}
+			result.WriteString(value)
+			s = s[end+1:]
+		default:
+			result.WriteByte('$')
+		}
+	}
+}
+
+// reference resolves what is between the braces of ${...}.
+func (e *expander) reference(ref string) (string, error) {
+	name, fallback, hasFallback := strings.Cut(ref, ":-")
+	if name == "" {
+		return "", fmt.Errorf("empty reference ${%s}", ref)
+	}
+
+	var value string
+	var ok bool
+	if env, isEnv := strings.CutPrefix(name, "env:"); isEnv {
+		if !validName(env) {
+			return "", fmt.Errorf("invalid variable name %q in ${%s}", env, ref)
+		}
+		value, ok = e.lookup(env)
+		name = env
+	} else if builtin, isBuiltin := e.builtins[name]; isBuiltin {
+		value, ok = builtin, true
+	} else if validName(name) {
+		value, ok = e.lookup(name)
+	} else {
+		return "", fmt.Errorf("invalid reference ${%s}, expected ${VAR}, ${VAR:-default}, ${env:VAR}, ${configDir} or ${profileName}", ref)
+	}
+
+	if hasFallback {
+		if value != "" {
+			return value, nil
+		}
+		return e.expand(fallback)
+	}
+	if !ok {
+		return "", errUnset(name)
+	}
+	return value, nil
+}
+
+func errUnset(name string) error {
+	return fmt.Errorf("environment variable %s is not set, use ${%s:-default} to allow that", name, name)
+}
+
+// closingBrace finds the brace that closes the one s starts with, nested
+// ${...} in defaults are skipped.
+func closingBrace(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func nameLength(s string) int {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9' {
+			continue
+		}
+		return i
+	}
+	return len(s)
+}
+
+func validName(name string) bool {
+	return name != "" && nameLength(name) == len(name)
+}
+
+// expandConfig expands command, args and cwd of a terminal.
+func (e *expander) expandConfig(config *TerminalConfig) error {
+	if !config.Verbatim {
+		command, err := e.expand(config.Command)
+		if err != nil {
+			return fmt.Errorf("command: %w", err)
+		}
+		config.Command = command
+
+		args := make([]string, len(config.Args))
+		for i, arg := range config.Args {
+			if args[i], err = e.expand(arg); err != nil {
+				return fmt.Errorf("args[%d]: %w", i, err)
+			}
+		}
+		config.Args = args
+	}
+
+	if config.Cwd != nil && !config.CwdVerbatim {
+		cwd, err := e.expand(*config.Cwd)
+		if err != nil {
+			return fmt.Errorf("cwd: %w", err)
+		}
+		config.Cwd = &cwd
+	}
+
+	hooks := map[string]*[]string{"preLaunch": &config.PreLaunch, "postExit": &config.PostExit}
+	for name, hook := range hooks {
+		command := make([]string, len(*hook))
+		for i, arg := range *hook {
+			var err error
+			if command[i], err = e.expand(arg); err != nil {
+				return fmt.Errorf("%s[%d]: %w", name, i, err)
+			}
+		}
+		*hook = command
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func testExpander() *expander {
+	env := map[string]string{"NAME": "term2", "EMPTY": "", "DIR": "/srv"}
+	return &expander{
+		lookup: func(name string) (string, bool) {
+			value, ok := env[name]
+			return value, ok
+		},
+		builtins: map[string]string{"configDir": "/config", "profileName": "bash"},
+		home:     func() (string, error) { return "/home/me", nil },
+	}
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  string
+	}{
+		{in: "plain", want: "plain"},
+		{in: "~", want: "/home/me"},
+		{in: "~/src", want: "/home/me/src"},
+		{in: `~\src`, want: `/home/me\src`},
+		{in: "~user", want: "~user"},
+		{in: "a/~", want: "a/~"},
+		{in: "${NAME}", want: "term2"},
+		{in: "x${NAME}y", want: "xterm2y"},
+		{in: "${env:NAME}", want: "term2"},
+		{in: "${configDir}/themes", want: "/config/themes"},
+		{in: "${profileName}", want: "bash"},
+		{in: "${NAME:-other}", want: "term2"},
+		{in: "${MISSING:-other}", want: "other"},
+		{in: "${EMPTY:-other}", want: "other"},
+		{in: "${MISSING:-${DIR}/x}", want: "/srv/x"},
+		{in: "${MISSING:-}", want: ""},
+		{in: "~/${NAME}", want: "/home/me/term2"},
+
+		// bare references belong to the shell
+		{in: "$NAME", want: "$NAME"},
+		{in: "$env:PATH", want: "$env:PATH"},
+		{in: "-Command $Host.UI", want: "-Command $Host.UI"},
+		{in: "$(pwd)", want: "$(pwd)"},
+		{in: "$$", want: "$$"},
+		{in: "cost: 5$", want: "cost: 5$"},
+		{in: "$${NAME}", want: "${NAME}"},
+
+		{in: "${MISSING}", err: "environment variable MISSING is not set"},
+		{in: "${env:configDir}", err: "environment variable configDir is not set"},
+		{in: "${NAME", err: "unclosed ${"},
+		{in: "${}", err: "empty reference"},
+		{in: "${:-x}", err: "empty reference"},
+		{in: "${a b}", err: "invalid reference ${a b}"},
+		{in: "${env:1x}", err: `invalid variable name "1x"`},
+		{in: "${MISSING:-${ALSO_MISSING}}", err: "ALSO_MISSING is not set"},
+	}
+	e := testExpander()
+	for _, test := range tests {
+		got, err := e.expand(test.in)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("expand(%q) = %q, %v, want error containing %q", test.in, got, err, test.err)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("expand(%q) = %q, %v, want %q", test.in, got, err, test.want)
+		}
+	}
+}
+
+func TestSyntaxExpander(t *testing.T) {
+	tests := []struct {
+		in    string
+		valid bool
+	}{
+		{"${ANYTHING}", true},
+		{"${configDir}/x", true},
+		{"$bare", true},
+		{"${", false},
+		{"${a b}", false},
+	}
+	for _, test := range tests {
+		_, err := syntaxExpander.expand(test.in)
+		if (err == nil) != test.valid {
+			t.Errorf("syntaxExpander.expand(%q) = %v, want valid %v", test.in, err, test.valid)
+		}
+	}
+}
+
+func TestExpandConfig(t *testing.T) {
+	cwd := "${DIR}/app"
+	tests := []struct {
+		name    string
+		config  TerminalConfig
+		command string
+		args    []string
+		cwd     string
+	}{
+		{
+			name:    "profile",
+			config:  TerminalConfig{Command: "${DIR}/sh", Args: []string{"-c", "$HOME"}, Cwd: &cwd},
+			command: "/srv/sh",
+			args:    []string{"-c", "$HOME"},
+			cwd:     "/srv/app",
+		},
+		{
+			name:    "verbatim command",
+			config:  TerminalConfig{Command: "${DIR}/sh", Args: []string{"${NAME}"}, Cwd: &cwd, Verbatim: true},
+			command: "${DIR}/sh",
+			args:    []string{"${NAME}"},
+			cwd:     "/srv/app",
+		},
+		{
+			name:    "verbatim cwd",
+			config:  TerminalConfig{Command: "sh", Cwd: &cwd, CwdVerbatim: true},
+			command: "sh",
+			args:    []string{},
+			cwd:     "${DIR}/app",
+		},
+	}
+	for _, test := range tests {
+		config := test.config
+		if err := testExpander().expandConfig(&config); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if config.Command != test.command || strings.Join(config.Args, " ") != strings.Join(test.args, " ") || *config.Cwd != test.cwd {
+			t.Errorf("%s: got %q %q in %q, want %q %q in %q", test.name, config.Command, config.Args, *config.Cwd, test.command, test.args, test.cwd)
+		}
+	}
+
+	config := TerminalConfig{Command: "sh", Args: []string{"${MISSING}"}}
+	if err := testExpander().expandConfig(&config); err == nil || !strings.HasPrefix(err.Error(), "args[0]: ") {
+		t.Errorf("expandConfig with an unset variable = %v, want an args[0] error", err)
+	}
+}
@@ -0,0 +1,60 @@
+import { IMarker, Terminal } from "@xterm/xterm";
+
+// Tracks the prompt marks emitted by the shell integration scripts. The
+// backend keeps the command records, the frontend only needs buffer positions.
+export function trackPrompts(terminal: Terminal) {
+  const prompts: IMarker[] = [];
+  terminal.parser.registerOscHandler(133, (data) => {
+    const [kind, exitCode] = data.split(";");
+    switch (kind) {
+      case "A": {
+        const marker = terminal.registerMarker(0);
+        if (!marker) break;
+        while (prompts.length > 0 && prompts[0].isDisposed) {
+          prompts.shift();
+        }
+        prompts.push(marker);
+        break;
+      }
+      case "D": {
+        const marker = prompts[prompts.length - 1];
+        if (!marker || marker.isDisposed || !exitCode || exitCode === "0") {
+          break;
+        }
+        terminal
+          .registerDecoration({
+            marker,
+            overviewRulerOptions: { color: "#ef4444" },
+          })
+          ?.onRender((element) => element.classList.add("failed-command"));
+        break;
+      }
+    }
+    return false;
+  });
+  return prompts;
+}
+
+export function scrollToPrompt(
+  terminal: Terminal,
+  prompts: IMarker[],
+  direction: "previous" | "next",
+) {
+  const top = terminal.buffer.active.viewportY;
+  let target: IMarker | undefined;
+  if (direction === "previous") {
+    for (let i = prompts.length - 1; i >= 0; i--) {
+      if (!prompts[i].isDisposed && prompts[i].line < top) {
+        target = prompts[i];
+        break;
+      }
+    }
+  } else {
+    target = prompts.find((marker) => !marker.isDisposed && marker.line > top);
+  }
+  if (target) {
+    terminal.scrollToLine(target.line);
+  } else if (direction === "next") {
+    terminal.scrollToBottom();
+  }
+}
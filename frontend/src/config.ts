@@ -1,4 +1,3 @@
-import { z } from "zod";
 import {
   createTerminal,
   ctrlTabOpen,
@@ -6,43 +5,43 @@ import {
   destroyTerminal,
   keys,
   multilineModal,
+  pushToast,
   store,
 } from "@/store";
-import { ClipboardGetText, ClipboardSetText } from "@@/wailsjs/runtime/runtime";
+import {
+  ClipboardGetText,
+  ClipboardSetText,
+  EventsOn,
+} from "@@/wailsjs/runtime/runtime";
 import {
   ExitWithErr,
+  GetCommandOutput,
   ReadConfigFile,
   OpenConfigFile,
+  OpenWorkspace,
+  ReopenClosedTerminal,
+  SaveWorkspace,
 } from "@@/wailsjs/go/main/App";
+import { main } from "@@/wailsjs/go/models";
+import { scrollToPrompt } from "@/commands";
 
 const profiles = new Map<string, Profile>();
 
 let defaultProfile = "";
 
-const scopes = new Map<
-  string,
-  Map<
-    number,
-    | string
-    | {
-        action: string;
-        setScope: string;
-      }
-  >
->();
-
-let shortcuts = new Map<
-  number,
-  | string
-  | {
-      action: string;
-      setScope: string;
-    }
->();
+type Binding = {
+  action: string;
+  setScope?: string;
+  workspace?: string;
+};
+
+const scopes = new Map<string, Map<number, string | Binding>>();
+
+let shortcuts = new Map<number, string | Binding>();
 
 const actions = new Map<
   string,
-  (e: KeyboardEvent | undefined, id: number) => boolean
+  (e: KeyboardEvent | undefined, id: number, binding?: Binding) => boolean
 >([
   [
     "newTerminal",
@@ -51,6 +50,15 @@ const actions = new Map<
       return false;
     },
   ],
+  [
+    "newTerminalHere",
+    (_, id) => {
+      createTerminal(store.get(id)?.profile ?? profiles.get(defaultProfile)!, {
+        cwdFrom: id,
+      });
+      return false;
+    },
+  ],
   [
     "closeTerminal",
     (_, id) => {
@@ -139,6 +147,31 @@ const actions = new Map<
       return false;
     },
   ],
+  [
+    "previousPrompt",
+    (_, id) => {
+      const { terminal, prompts } = store.get(id)!;
+      scrollToPrompt(terminal, prompts, "previous");
+      return false;
+    },
+  ],
+  [
+    "nextPrompt",
+    (_, id) => {
+      const { terminal, prompts } = store.get(id)!;
+      scrollToPrompt(terminal, prompts, "next");
+      return false;
+    },
+  ],
+  [
+    "copyLastCommandOutput",
+    (_, id) => {
+      GetCommandOutput(id, -1)
+        .then((output) => ClipboardSetText(output))
+        .catch(console.error);
+      return false;
+    },
+  ],
   [
     "openConfigFile",
     () => {
@@ -146,6 +179,62 @@ const actions = new Map<
       return false;
     },
   ],
+  [
+    "openWorkspace",
+    (_, id, binding) => {
+      OpenWorkspace(binding?.workspace ?? "", id)
+        .then(openTerminals)
+        .catch((error) => {
+          pushToast({
+            title: "Couldn't open workspace",
+            body: String(error),
+            level: "error",
+          });
+        });
+      return false;
+    },
+  ],
+  [
+    "saveWorkspace",
+    (_, __, binding) => {
+      SaveWorkspace(binding?.workspace ?? "", Array.from(keys.value.keys()))
+        .then((file) => {
+          if (file) {
+            pushToast({
+              title: "Saved workspace",
+              body: file,
+              level: "success",
+            });
+          }
+        })
+        .catch((error) => {
+          pushToast({
+            title: "Couldn't save workspace",
+            body: String(error),
+            level: "error",
+          });
+        });
+      return false;
+    },
+  ],
+  [
+    "reopenClosedTerminal",
+    () => {
+      ReopenClosedTerminal()
+        .then((request) => {
+          if (request) {
+            openTerminal(request, "reopened");
+          } else {
+            pushToast({
+              title: "No closed terminals to reopen",
+              level: "info",
+            });
+          }
+        })
+        .catch(console.error);
+      return false;
+    },
+  ],
 ]);
 
 export type KeyEvent = {
@@ -431,8 +520,10 @@ export function handleEvent(e: KeyboardEvent | KeyEvent, id: number): boolean {
       }
       break;
     case "object":
-      temp = actions.get(entry.action)!(event, id);
-      shortcuts = scopes.get(entry.setScope) ?? shortcuts;
+      temp = actions.get(entry.action)!(event, id, entry);
+      if (entry.setScope) {
+        shortcuts = scopes.get(entry.setScope) ?? shortcuts;
+      }
       break;
     case "undefined":
       return true;
@@ -449,6 +540,45 @@ export function handleEvent(e: KeyboardEvent | KeyEvent, id: number): boolean {
   return temp;
 }
 
+export async function openTerminals(requests: main.TerminalRequest[]) {
+  // one after the other so the tabs keep their order
+  for (const request of requests) {
+    await openTerminal(request);
+  }
+}
+
+EventsOn("terminals:open", openTerminals);
+
+export function openTerminal(
+  request: main.TerminalRequest,
+  scrollbackNote?: string,
+) {
+  let profile = profiles.get(request.profile);
+  if (!profile) {
+    if (request.profile) {
+      pushToast({
+        title: `Unknown profile ${request.profile}`,
+        body: `Opened ${defaultProfile} instead`,
+        level: "error",
+      });
+    }
+    profile = profiles.get(defaultProfile)!;
+  }
+  return createTerminal(profile, {
+    cwd: request.cwd ?? undefined,
+    input: request.input ?? undefined,
+    title: request.title,
+    command: request.command?.length ? request.command : undefined,
+    ticket: request.ticket,
+    env: request.env,
+    scrollback: request.scrollback,
+    scrollbackNote,
+    expandCwd: request.expandCwd,
+  });
+}
+
+EventsOn("terminal:open", openTerminal);
+
 export function triggerAction(actionKey: string, id: number) {
   const action = actions.get(actionKey);
   if (!action) {
@@ -458,122 +588,84 @@ export function triggerAction(actionKey: string, id: number) {
   return action(undefined, id);
 }
 
-const shortcutSchema = z.object({
-  code: z
-    .string({
-      message: "code: not a string",
-    })
-    .refine((value) => codeToNumber.has(value), {
-      message: "code: invalid",
-    }),
-  type: z
-    .enum(["keydown", "keyup"], {
-      message: "type: invalid",
-    })
-    .optional()
-    .default("keydown"),
-  ctrlKey: z
-    .boolean({ message: "ctrlKey: not a boolean" })
-    .optional()
-    .default(false),
-  shiftKey: z
-    .boolean({
-      message: "shiftKey: not a boolean",
-    })
-    .optional()
-    .default(false),
-  altKey: z
-    .boolean({
-      message: "altKey: not a boolean",
-    })
-    .optional()
-    .default(false),
-});
-
-const profileSchema = z.object({
-  name: z.string(),
-  command: z.string(),
-  args: z.array(z.string()),
-  cwd: z.string().optional(),
-  font: z.string(),
-  fontSize: z.number(),
-  logo: z.string(),
-  backgroundImage: z.string(),
-  theme: z.object({
-    background: z.string(),
-    selectionBackground: z.string(),
-    selectionInactiveBackground: z.string(),
-  }),
-  shortcut: shortcutSchema,
-});
-
-export type Profile = Omit<z.infer<typeof profileSchema>, "shortcut">;
+export type Profile = main.Profile;
 
 export async function loadConfig() {
-  const fileSchema = z.object({
-    fonts: z.array(
-      z.object({
-        name: z.string(),
-        url: z.string(),
-      }),
-    ),
-    defaultProfile: z.string(),
-    profiles: z.array(profileSchema),
-    defaultScope: z.string(),
-    shortcuts: z.array(
-      z.object({
-        shortcut: shortcutSchema,
-        action: z
-          .string({
-            message: "action: not a string",
-          })
-          .refine((value) => actions.has(value), {
-            message: "action: invalid",
-          }),
-        scopes: z
-          .array(
-            z.string({
-              message: "scopes: not a string",
-            }),
-            {
-              message: "scopes: not an array",
-            },
-          )
-          .optional()
-          .default(["default"]),
-        setScope: z
-          .string({
-            message: "setScope: not a string",
-          })
-          .optional(),
-      }),
-    ),
-  });
-
-  const file = await ReadConfigFile();
-  let data: z.infer<typeof fileSchema> | undefined;
+  let data: main.Config;
   try {
-    data = fileSchema.parse(JSON.parse(file));
+    data = await ReadConfigFile();
   } catch (error) {
-    if (error instanceof z.ZodError) {
-      await ExitWithErr(error.errors.map((e) => e.message).join("\n"));
-      return;
-    }
-    if (error instanceof SyntaxError) {
-      await ExitWithErr("Invalid JSON");
-      return;
-    }
+    await ExitWithErr(String(error));
     return;
   }
+  applyConfig(data);
+  return data;
+}
+
+type ConfigChange = {
+  config: main.Config;
+  added: string[];
+  removed: string[];
+  changed: string[];
+  shortcuts: boolean;
+  fonts: boolean;
+};
+
+EventsOn("config", (change: ConfigChange) => {
+  applyConfig(main.Config.createFrom(change.config));
+
+  // running terminals keep their process, only the looks are updated
+  for (const entry of store.values()) {
+    const profile = profiles.get(entry.profile.name);
+    if (!profile || !change.changed.includes(profile.name)) {
+      continue;
+    }
+    entry.profile = profile;
+    entry.logoUrl = profile.logo;
+    entry.backgroundUrl = profile.backgroundImage;
+    entry.terminal.options.theme = profile.theme;
+    entry.terminal.options.fontFamily = profile.font;
+    entry.terminal.options.fontSize = profile.fontSize;
+    entry.fitAddon.fit();
+  }
+
+  const parts = [
+    ...change.added.map((name) => `added ${name}`),
+    ...change.removed.map((name) => `removed ${name}`),
+    ...change.changed.map((name) => `updated ${name}`),
+  ];
+  if (change.shortcuts) parts.push("updated shortcuts");
+  if (change.fonts) parts.push("updated fonts");
+  pushToast({
+    title: "Config reloaded",
+    body: parts.join(", ") || undefined,
+    level: "success",
+  });
+});
+
+EventsOn("config:error", (error: string) => {
+  pushToast({
+    title: "Config not applied",
+    body: error,
+    level: "error",
+  });
+});
+
+let fontStyle: Ref<string> | undefined;
+
+function applyConfig(data: main.Config) {
+  scopes.clear();
+  profiles.clear();
 
-  const defaultScope = shortcuts;
+  const defaultScope: typeof shortcuts = new Map();
   scopes.set(data.defaultScope, defaultScope);
 
   for (const {
     shortcut,
     action,
-    scopes: _scopes,
+    scopes: _scopes = ["default"],
     setScope,
+    workspace,
   } of data.shortcuts) {
     const key = eventToShortcut(shortcut);
     if (key === Code.None) {
@@ -586,10 +678,11 @@ export async function loadConfig() {
       }
       scopes.get(scope)!.set(
         key,
-        setScope ?
+        setScope || workspace ?
           {
             action,
             setScope,
+            workspace,
           }
         : action,
       );
@@ -598,8 +691,6 @@ export async function loadConfig() {
 
   for (const profile of data.profiles) {
     defaultScope.set(eventToShortcut(profile.shortcut), profile.name);
-    // @ts-ignore
-    delete profile.shortcut;
     profiles.set(profile.name, profile);
   }
 
@@ -607,18 +698,19 @@ export async function loadConfig() {
 
   shortcuts = scopes.get(data.defaultScope)!;
 
-  useStyleTag(
-    data.fonts
-      .map((font) => {
-        return `
+  const css = data.fonts
+    .map((font) => {
+      return `
 @font-face {
 font-family: '${font.name}';
 src: url('@term2${font.url}');
 }`;
-      })
-      .join("\n"),
-  );
-
-  return data;
+    })
+    .join("\n");
+  if (fontStyle) {
+    fontStyle.value = css;
+  } else {
+    fontStyle = useStyleTag(css).css;
+  }
 }
 // .then(() =>{console.log("Shortcuts loaded")});
@@ -1,12 +1,19 @@
-import { Terminal } from "@xterm/xterm";
+import { IMarker, Terminal } from "@xterm/xterm";
 import { FitAddon } from "@xterm/addon-fit";
 import { SerializeAddon } from "@xterm/addon-serialize";
 
-import { ConsoleLog, CreateTerminal } from "@@/wailsjs/go/main/App";
+import {
+  ConsoleLog,
+  CreateTerminal,
+  GetTitle,
+  SetActiveTerminal,
+  SetTabOrder,
+} from "@@/wailsjs/go/main/App";
+import { EventsOn } from "@@/wailsjs/runtime/runtime";
 import { main } from "@@/wailsjs/go/models";
-import { clipboardAddon as ClipboardAddon } from "@/lib/utils";
 import Pty from "@/pty";
 import { handleEvent, Profile, triggerAction } from "@/config";
+import { trackPrompts } from "@/commands";
 
 export type StoreEntry = {
   terminal: Terminal;
@@ -14,11 +21,19 @@ export type StoreEntry = {
   fitAddon: FitAddon;
   serializeAddon: SerializeAddon;
   mode: Ref<"normal" | "fullscreen">;
-  title: string;
+  prompts: IMarker[];
+  progress: Ref<Progress>;
+  profile: Profile;
+  title: Ref<string>;
   logoUrl: string;
   backgroundUrl: string;
 };
 
+export type Progress = {
+  state: "none" | "normal" | "error" | "indeterminate" | "paused";
+  percent: number;
+};
+
 export const store = new Map<number, StoreEntry>();
 
 export const keys = ref(new Map<number, boolean>());
@@ -29,11 +44,102 @@ export const multilineModal = ref<boolean | string[]>(false);
 
 export const ctrlTabOpen = ref(false);
 
+export type Toast = {
+  id: number;
+  title: string;
+  body?: string;
+  level: "info" | "success" | "error";
+  terminal?: number;
+};
+
+type TerminalNotification = {
+  terminal: number;
+  title: string;
+  body: string;
+  level: Toast["level"];
+};
+
+export const toasts = ref<Toast[]>([]);
+
+let toastId = 0;
+
+const TOAST_TIMEOUT = 8000;
+
+export function pushToast(toast: Omit<Toast, "id">) {
+  const id = toastId++;
+  toasts.value.push({ ...toast, id });
+  setTimeout(() => dismissToast(id), TOAST_TIMEOUT);
+}
+
+export function dismissToast(id: number) {
+  toasts.value = toasts.value.filter((toast) => toast.id !== id);
+}
+
+EventsOn("terminal:notification", (notification: TerminalNotification) => {
+  if (notification.terminal === currentTerminal.value && document.hasFocus()) {
+    return;
+  }
+  pushToast({
+    title: notification.title,
+    body: notification.body,
+    level: notification.level,
+    terminal: notification.terminal,
+  });
+});
+
+EventsOn("terminal:progress", (id: number, progress: Progress) => {
+  const entry = store.get(id);
+  if (entry) {
+    entry.progress.value = progress;
+  }
+});
+
+EventsOn("terminal:title", (id: number, title: string) => {
+  const entry = store.get(id);
+  if (entry) {
+    entry.title.value = title;
+  }
+});
+
+watch(currentTerminal, (id) => {
+  SetActiveTerminal(id).catch(console.error);
+});
+
+watch(
+  keys,
+  (keys) => {
+    SetTabOrder(Array.from(keys.keys())).catch(console.error);
+  },
+  { deep: true },
+);
+
+EventsOn("terminal:focus", (id: number) => {
+  if (store.has(id)) {
+    currentTerminal.value = id;
+  }
+});
+
 const CALLBACK_BYTE_LIMIT = 100000;
 const HIGH = 5;
 const LOW = 2;
 
-export async function createTerminal(profile: Profile) {
+export type TerminalOptions = {
+  cwdFrom?: number;
+  cwd?: string;
+  input?: string;
+  title?: string;
+  command?: string[];
+  ticket?: number;
+  env?: main.TerminalRequest["env"];
+  scrollback?: string; // base64
+  scrollbackNote?: string;
+  expandCwd?: boolean;
+};
+
+export async function createTerminal(
+  profile: Profile,
+  options?: TerminalOptions,
+) {
   const terminal = new Terminal({
     fontFamily: profile.font,
     fontSize: profile.fontSize,
@@ -43,22 +149,75 @@ export async function createTerminal(profile: Profile) {
   });
   const fitAddon = new FitAddon();
   const serializeAddon = new SerializeAddon();
-  const clipboardAddon = ClipboardAddon();
   terminal.loadAddon(fitAddon);
   terminal.loadAddon(serializeAddon);
-  terminal.loadAddon(clipboardAddon);
+  const prompts = trackPrompts(terminal);
 
   const config = new main.TerminalConfig();
-  config.command = profile.command;
-  config.args = profile.args;
-  config.cwd = profile.cwd;
+  config.command = options?.command?.[0] ?? profile.command;
+  config.args = options?.command?.slice(1) ?? profile.args;
+  config.cwd = options?.cwd ?? profile.cwd;
+  // only the profile's cwd is expanded, others are paths unless they come
+  // from a workspace file
+  config.cwdVerbatim = options?.cwd !== undefined && !options.expandCwd;
+  config.inheritCwdFrom =
+    options?.cwdFrom ??
+    (options?.cwd === undefined &&
+    profile.inheritCwd &&
+    currentTerminal.value !== -1 ?
+      currentTerminal.value
+    : undefined);
+  config.shellIntegration = profile.shellIntegration;
+  config.profile = profile.name;
+  config.commandNotification = profile.commandNotification;
+  config.notifications = profile.notifications;
+  config.clipboard = profile.clipboard;
+  config.titleTemplate = profile.titleTemplate;
+  config.env = { ...profile.env, ...options?.env };
+  config.envFiles = profile.envFiles;
+  config.pathPrepend = profile.pathPrepend;
+  config.pathAppend = profile.pathAppend;
+  if (!options?.command) {
+    // meant for the profile's shell, not for a command that replaces it
+    config.startupInput = profile.startupInput;
+    config.startupPattern = profile.startupPattern;
+  }
+  config.preLaunch = profile.preLaunch;
+  config.postExit = profile.postExit;
+  config.input = options?.input;
+  config.title = options?.title;
+  config.verbatim = !!options?.command;
+  config.ticket = options?.ticket;
 
-  const id = await CreateTerminal(config);
+  let id: number;
+  try {
+    id = await CreateTerminal(config);
+  } catch (error) {
+    terminal.dispose();
+    pushToast({
+      title: `Couldn't start ${profile.name}`,
+      body: String(error),
+      level: "error",
+    });
+    return;
+  }
 
   terminal.attachCustomKeyEventHandler((event) => {
     return handleEvent(event, id);
   });
 
+  if (options?.scrollback) {
+    // base64 so output that isn't valid UTF-8 comes through as is, leave
+    // whatever it left behind afterwards, e.g. an alternate screen
+    terminal.write(
+      Uint8Array.from(atob(options.scrollback), (c) => c.charCodeAt(0)),
+    );
+    const note = options.scrollbackNote ?? "restored session";
+    terminal.write(
+      `\x1b[?1049l\x1b[0m\r\n\x1b[2m──── ${note} ────\x1b[0m\r\n`,
+    );
+  }
+
   const pty = await Pty.create(id);
 
   let written = 0;
@@ -95,12 +254,23 @@ export async function createTerminal(profile: Profile) {
     fitAddon,
     serializeAddon,
     mode: ref("normal"),
-    title: profile.name,
+    prompts,
+    progress: ref({ state: "none", percent: 0 }),
+    profile,
+    title: ref(options?.title || profile.name),
     logoUrl: profile.logo,
     backgroundUrl: profile.backgroundImage,
   });
   keys.value.set(id, false);
   currentTerminal.value = id;
+  GetTitle(id)
+    .then((title) => {
+      const entry = store.get(id);
+      if (entry) {
+        entry.title.value = title;
+      }
+    })
+    .catch(console.error);
 }
 
 export function destroyTerminal(id: number, options?: { fromExit?: boolean }) {
@@ -8,8 +8,36 @@ export function CreateTerminal(arg1:main.TerminalConfig):Promise<number>;
 
 export function ExitWithErr(arg1:string):Promise<void>;
 
+export function GetCommandOutput(arg1:number,arg2:number):Promise<string>;
+
+export function GetCommands(arg1:number):Promise<Array<main.CommandRecord>>;
+
 export function GetDetails(arg1:number):Promise<string>;
 
+export function GetEnvironment(arg1:number):Promise<Array<string>>;
+
+export function GetLaunchRequests():Promise<Array<main.TerminalRequest>>;
+
+export function GetLoginEnvReport():Promise<main.LoginEnvReport>;
+
+export function GetTitle(arg1:number):Promise<string>;
+
 export function OpenConfigFile():Promise<void>;
 
-export function ReadConfigFile():Promise<string>;
+export function OpenWorkspace(arg1:string,arg2:number):Promise<Array<main.TerminalRequest>>;
+
+export function ReadConfigFile():Promise<main.Config>;
+
+export function ReopenClosedTerminal():Promise<main.TerminalRequest>;
+
+export function RestoreSession():Promise<Array<main.TerminalRequest>>;
+
+export function RunHistoryEntry(arg1:main.HistoryEntry,arg2:number):Promise<void>;
+
+export function SaveWorkspace(arg1:string,arg2:Array<number>):Promise<string>;
+
+export function SearchHistory(arg1:main.HistoryQuery):Promise<Array<main.HistoryEntry>>;
+
+export function SetActiveTerminal(arg1:number):Promise<void>;
+
+export function SetTabOrder(arg1:Array<number>):Promise<void>;
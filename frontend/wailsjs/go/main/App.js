@@ -14,14 +14,70 @@ export function ExitWithErr(arg1) {
   return window['go']['main']['App']['ExitWithErr'](arg1);
 }
 
+export function GetCommandOutput(arg1, arg2) {
+  return window['go']['main']['App']['GetCommandOutput'](arg1, arg2);
+}
+
+export function GetCommands(arg1) {
+  return window['go']['main']['App']['GetCommands'](arg1);
+}
+
 export function GetDetails(arg1) {
   return window['go']['main']['App']['GetDetails'](arg1);
 }
 
+export function GetEnvironment(arg1) {
+  return window['go']['main']['App']['GetEnvironment'](arg1);
+}
+
+export function GetLaunchRequests() {
+  return window['go']['main']['App']['GetLaunchRequests']();
+}
+
+export function GetLoginEnvReport() {
+  return window['go']['main']['App']['GetLoginEnvReport']();
+}
+
+export function GetTitle(arg1) {
+  return window['go']['main']['App']['GetTitle'](arg1);
+}
+
 export function OpenConfigFile() {
   return window['go']['main']['App']['OpenConfigFile']();
 }
 
+export function OpenWorkspace(arg1,arg2) {
+  return window['go']['main']['App']['OpenWorkspace'](arg1,arg2);
+}
+
 export function ReadConfigFile() {
   return window['go']['main']['App']['ReadConfigFile']();
 }
+
+export function ReopenClosedTerminal() {
+  return window['go']['main']['App']['ReopenClosedTerminal']();
+}
+
+export function RestoreSession() {
+  return window['go']['main']['App']['RestoreSession']();
+}
+
+export function RunHistoryEntry(arg1, arg2) {
+  return window['go']['main']['App']['RunHistoryEntry'](arg1, arg2);
+}
+
+export function SaveWorkspace(arg1,arg2) {
+  return window['go']['main']['App']['SaveWorkspace'](arg1,arg2);
+}
+
+export function SearchHistory(arg1) {
+  return window['go']['main']['App']['SearchHistory'](arg1);
+}
+
+export function SetActiveTerminal(arg1) {
+  return window['go']['main']['App']['SetActiveTerminal'](arg1);
+}
+
+export function SetTabOrder(arg1) {
+  return window['go']['main']['App']['SetTabOrder'](arg1);
+}
@@ -1,5 +1,348 @@
 export namespace main {
 	
+	export class Automation {
+	    enabled: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Automation(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	    }
+	}
+	export class ClosedTerminals {
+	    limit?: number;
+	    persist?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ClosedTerminals(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.limit = source["limit"];
+	        this.persist = source["persist"];
+	    }
+	}
+	export class CommandNotification {
+	    threshold?: number;
+	    desktop?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new CommandNotification(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.threshold = source["threshold"];
+	        this.desktop = source["desktop"];
+	    }
+	}
+	export class CommandRecord {
+	    id: number;
+	    command: string;
+	    cwd: string;
+	    // Go type: time
+	    start: any;
+	    // Go type: time
+	    end?: any;
+	    exitCode?: number;
+	    outputStart: number;
+	    outputEnd: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new CommandRecord(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.command = source["command"];
+	        this.cwd = source["cwd"];
+	        this.start = this.convertValues(source["start"], null);
+	        this.end = this.convertValues(source["end"], null);
+	        this.exitCode = source["exitCode"];
+	        this.outputStart = source["outputStart"];
+	        this.outputEnd = source["outputEnd"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Config {
+	    version?: number;
+	    fonts: Font[];
+	    defaultProfile: string;
+	    profiles: Profile[];
+	    defaultScope: string;
+	    shortcuts: ShortcutBinding[];
+	    loginShellEnv?: LoginShellEnv;
+	    automation?: Automation;
+	    session?: SessionSettings;
+	    closedTerminals?: ClosedTerminals;
+	
+	    static createFrom(source: any = {}) {
+	        return new Config(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.version = source["version"];
+	        this.fonts = this.convertValues(source["fonts"], Font);
+	        this.defaultProfile = source["defaultProfile"];
+	        this.profiles = this.convertValues(source["profiles"], Profile);
+	        this.defaultScope = source["defaultScope"];
+	        this.shortcuts = this.convertValues(source["shortcuts"], ShortcutBinding);
+	        this.loginShellEnv = this.convertValues(source["loginShellEnv"], LoginShellEnv);
+	        this.automation = this.convertValues(source["automation"], Automation);
+	        this.session = this.convertValues(source["session"], SessionSettings);
+	        this.closedTerminals = this.convertValues(source["closedTerminals"], ClosedTerminals);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Font {
+	    name: string;
+	    url: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Font(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.url = source["url"];
+	    }
+	}
+	export class HistoryEntry {
+	    command: string;
+	    profile: string;
+	    host: string;
+	    cwd: string;
+	    exitCode?: number;
+	    // Go type: time
+	    start: any;
+	    duration: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new HistoryEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.command = source["command"];
+	        this.profile = source["profile"];
+	        this.host = source["host"];
+	        this.cwd = source["cwd"];
+	        this.exitCode = source["exitCode"];
+	        this.start = this.convertValues(source["start"], null);
+	        this.duration = source["duration"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class HistoryQuery {
+	    text: string;
+	    mode: string;
+	    cwd: string;
+	    status: string;
+	    profile: string;
+	    unique: boolean;
+	    limit: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new HistoryQuery(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.text = source["text"];
+	        this.mode = source["mode"];
+	        this.cwd = source["cwd"];
+	        this.status = source["status"];
+	        this.profile = source["profile"];
+	        this.unique = source["unique"];
+	        this.limit = source["limit"];
+	    }
+	}
+	export class LoginEnvReport {
+	    shell: string;
+	    source: string;
+	    error?: string;
+	    duration: number;
+	    added: string[];
+	    changed: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new LoginEnvReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.shell = source["shell"];
+	        this.source = source["source"];
+	        this.error = source["error"];
+	        this.duration = source["duration"];
+	        this.added = source["added"];
+	        this.changed = source["changed"];
+	    }
+	}
+	export class LoginShellEnv {
+	    enabled: boolean;
+	    shell?: string;
+	    timeout?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new LoginShellEnv(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.shell = source["shell"];
+	        this.timeout = source["timeout"];
+	    }
+	}
+	export class NotificationPolicy {
+	    osc?: string;
+	    bell?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new NotificationPolicy(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.osc = source["osc"];
+	        this.bell = source["bell"];
+	    }
+	}
+	export class Profile {
+	    name: string;
+	    command: string;
+	    args: string[];
+	    cwd?: string;
+	    inheritCwd?: boolean;
+	    shellIntegration?: boolean;
+	    commandNotification?: CommandNotification;
+	    notifications?: NotificationPolicy;
+	    clipboard?: string;
+	    titleTemplate?: string;
+	    env?: {[key: string]: string};
+	    envFiles?: string[];
+	    pathPrepend?: string[];
+	    pathAppend?: string[];
+	    startupInput?: string;
+	    startupPattern?: string;
+	    preLaunch?: string[];
+	    postExit?: string[];
+	    font: string;
+	    fontSize: number;
+	    logo: string;
+	    backgroundImage: string;
+	    theme: Theme;
+	    shortcut: Shortcut;
+	
+	    static createFrom(source: any = {}) {
+	        return new Profile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.command = source["command"];
+	        this.args = source["args"];
+	        this.cwd = source["cwd"];
+	        this.inheritCwd = source["inheritCwd"];
+	        this.shellIntegration = source["shellIntegration"];
+	        this.commandNotification = this.convertValues(source["commandNotification"], CommandNotification);
+	        this.notifications = this.convertValues(source["notifications"], NotificationPolicy);
+	        this.clipboard = source["clipboard"];
+	        this.titleTemplate = source["titleTemplate"];
+	        this.env = source["env"];
+	        this.envFiles = source["envFiles"];
+	        this.pathPrepend = source["pathPrepend"];
+	        this.pathAppend = source["pathAppend"];
+	        this.startupInput = source["startupInput"];
+	        this.startupPattern = source["startupPattern"];
+	        this.preLaunch = source["preLaunch"];
+	        this.postExit = source["postExit"];
+	        this.font = source["font"];
+	        this.fontSize = source["fontSize"];
+	        this.logo = source["logo"];
+	        this.backgroundImage = source["backgroundImage"];
+	        this.theme = this.convertValues(source["theme"], Theme);
+	        this.shortcut = this.convertValues(source["shortcut"], Shortcut);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class PtySize {
 	    rows: number;
 	    cols: number;
@@ -18,11 +361,103 @@ export namespace main {
 	        this.pixelHeight = source["pixelHeight"];
 	    }
 	}
+	export class SessionSettings {
+	    restore?: string;
+	    scrollback?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SessionSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.restore = source["restore"];
+	        this.scrollback = source["scrollback"];
+	    }
+	}
+	export class Shortcut {
+	    code: string;
+	    type?: string;
+	    ctrlKey?: boolean;
+	    shiftKey?: boolean;
+	    altKey?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Shortcut(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.code = source["code"];
+	        this.type = source["type"];
+	        this.ctrlKey = source["ctrlKey"];
+	        this.shiftKey = source["shiftKey"];
+	        this.altKey = source["altKey"];
+	    }
+	}
+	export class ShortcutBinding {
+	    shortcut: Shortcut;
+	    action: string;
+	    scopes?: string[];
+	    setScope?: string;
+	    workspace?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ShortcutBinding(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.shortcut = this.convertValues(source["shortcut"], Shortcut);
+	        this.action = source["action"];
+	        this.scopes = source["scopes"];
+	        this.setScope = source["setScope"];
+	        this.workspace = source["workspace"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class TerminalConfig {
 	    size?: PtySize;
 	    command: string;
 	    args: string[];
 	    cwd?: string;
+	    cwdVerbatim?: boolean;
+	    inheritCwdFrom?: number;
+	    shellIntegration?: boolean;
+	    profile: string;
+	    input?: string;
+	    commandNotification?: CommandNotification;
+	    notifications?: NotificationPolicy;
+	    clipboard?: string;
+	    titleTemplate?: string;
+	    title?: string;
+	    verbatim?: boolean;
+	    ticket?: number;
+	    env?: {[key: string]: string};
+	    envFiles?: string[];
+	    pathPrepend?: string[];
+	    pathAppend?: string[];
+	    startupInput?: string;
+	    startupPattern?: string;
+	    preLaunch?: string[];
+	    postExit?: string[];
 	
 	    static createFrom(source: any = {}) {
 	        return new TerminalConfig(source);
@@ -34,6 +469,26 @@ export namespace main {
 	        this.command = source["command"];
 	        this.args = source["args"];
 	        this.cwd = source["cwd"];
+	        this.cwdVerbatim = source["cwdVerbatim"];
+	        this.inheritCwdFrom = source["inheritCwdFrom"];
+	        this.shellIntegration = source["shellIntegration"];
+	        this.profile = source["profile"];
+	        this.input = source["input"];
+	        this.commandNotification = this.convertValues(source["commandNotification"], CommandNotification);
+	        this.notifications = this.convertValues(source["notifications"], NotificationPolicy);
+	        this.clipboard = source["clipboard"];
+	        this.titleTemplate = source["titleTemplate"];
+	        this.title = source["title"];
+	        this.verbatim = source["verbatim"];
+	        this.ticket = source["ticket"];
+	        this.env = source["env"];
+	        this.envFiles = source["envFiles"];
+	        this.pathPrepend = source["pathPrepend"];
+	        this.pathAppend = source["pathAppend"];
+	        this.startupInput = source["startupInput"];
+	        this.startupPattern = source["startupPattern"];
+	        this.preLaunch = source["preLaunch"];
+	        this.postExit = source["postExit"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -54,6 +509,50 @@ export namespace main {
 		    return a;
 		}
 	}
+	export class TerminalRequest {
+	    profile: string;
+	    cwd?: string;
+	    input?: string;
+	    title?: string;
+	    command?: string[];
+	    ticket?: number;
+	    env?: {[key: string]: string};
+	    scrollback?: string;
+	    expandCwd?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new TerminalRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.profile = source["profile"];
+	        this.cwd = source["cwd"];
+	        this.input = source["input"];
+	        this.title = source["title"];
+	        this.command = source["command"];
+	        this.ticket = source["ticket"];
+	        this.env = source["env"];
+	        this.scrollback = source["scrollback"];
+	        this.expandCwd = source["expandCwd"];
+	    }
+	}
+	export class Theme {
+	    background: string;
+	    selectionBackground: string;
+	    selectionInactiveBackground: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Theme(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.background = source["background"];
+	        this.selectionBackground = source["selectionBackground"];
+	        this.selectionInactiveBackground = source["selectionInactiveBackground"];
+	    }
+	}
 
 }
 
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const maxHistoryEntries = 100000
+
+type HistoryEntry struct {
+	Command  string    `json:"command"`
+	Profile  string    `json:"profile"`
+	Host     string    `json:"host"`
+	Cwd      string    `json:"cwd"`
+	ExitCode *int      `json:"exitCode"`
+	Start    time.Time `json:"start"`
+	Duration int64     `json:"duration"` // milliseconds
+}
+
+type HistoryQuery struct {
+	Text    string `json:"text"`
+	Mode    string `json:"mode"` // substring (default), prefix, fuzzy or regex
+	Cwd     string `json:"cwd"`
+	Status  string `json:"status"` // any (default), success or failure
+	Profile string `json:"profile"`
+	Unique  bool   `json:"unique"`
+	Limit   int    `json:"limit"`
+}
+
+// History is the cross-session command history, stored as JSON lines. Lines
+// are appended off the read threads and the file is compacted to the last
+// maxHistoryEntries once it has twice as many.
+type History struct {
+	path    string
+	host    string
+	entries []HistoryEntry
+	pending [][]byte // lines that are not in the file yet
+	mutex   sync.Mutex
+
+	wake      chan struct{}
+	fileMutex sync.Mutex
+	lines     int // in the file, guarded by fileMutex
+}
+
+func loadHistory(path string) (*History, error) {
+	history := &History{path: path, wake: make(chan struct{}, 1)}
+	history.host, _ = os.Hostname()
+	go history.writeThread()
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return history, nil
+	}
+	if err != nil {
+		return history, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		history.lines++
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			logger.Printf("Skipping bad history line: %v\n", err)
+			continue
+		}
+		history.entries = append(history.entries, entry)
+	}
+	if len(history.entries) > maxHistoryEntries {
+		history.entries = history.entries[len(history.entries)-maxHistoryEntries:]
+	}
+	return history, scanner.Err()
+}
+
+func (h *History) Add(entry HistoryEntry) {
+	entry.Host = h.host
+	line, err := json.Marshal(entry)
+	if err != nil {
+		logger.Println(err)
+		return
+	}
+
+	h.mutex.Lock()
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > maxHistoryEntries+maxHistoryEntries/10 {
+		h.entries = append(h.entries[:0:0], h.entries[len(h.entries)-maxHistoryEntries:]...)
+	}
+	h.pending = append(h.pending, append(line, '\n'))
+	h.mutex.Unlock()
+
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (h *History) writeThread() {
+	for range h.wake {
+		h.Flush()
+	}
+}
+
+// Flush writes the pending lines, or the whole history when the file has
+// grown past twice the limit.
+func (h *History) Flush() {
+	h.fileMutex.Lock()
+	defer h.fileMutex.Unlock()
+
+	h.mutex.Lock()
+	pending := h.pending
+	h.pending = nil
+	var entries []HistoryEntry
+	if h.lines+len(pending) > 2*maxHistoryEntries {
+		entries = slices.Clone(h.entries)
+	}
+	h.mutex.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		logger.Println(err)
+		return
+	}
+	if entries != nil {
+		var b bytes.Buffer
+		encoder := json.NewEncoder(&b)
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				logger.Println(err)
+				return
+			}
+		}
+		if err := writeFileAtomic(h.path, b.Bytes(), 0600); err != nil {
+			logger.Println(err)
+			return
+		}
+		h.lines = len(entries)
+		return
+	}
+
+	file, err := os.OpenFile(h.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		logger.Println(err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(bytes.Join(pending, nil)); err != nil {
+		logger.Println(err)
+		return
+	}
+	h.lines += len(pending)
+}
+
+// Search returns the matching entries, most recent first. Fuzzy matches are
+// ordered by score instead.
+func (h *History) Search(query HistoryQuery) ([]HistoryEntry, error) {
+	var match func(command string) (int, bool)
+	switch query.Mode {
+	case "", "substring":
+		match = func(command string) (int, bool) {
+			return 0, strings.Contains(strings.ToLower(command), strings.ToLower(query.Text))
+		}
+	case "prefix":
+		match = func(command string) (int, bool) {
+			return 0, strings.HasPrefix(command, query.Text)
+		}
+	case "fuzzy":
+		match = func(command string) (int, bool) {
+			return fuzzyScore(command, query.Text)
+		}
+	case "regex":
+		re, err := regexp.Compile(query.Text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid history regex: %w", err)
+		}
+		match = func(command string) (int, bool) {
+			return 0, re.MatchString(command)
+		}
+	default:
+		return nil, fmt.Errorf("unknown history search mode %q", query.Mode)
+	}
+
+	type result struct {
+		entry HistoryEntry
+		score int
+	}
+	results := make([]result, 0)
+	seen := make(map[string]bool)
+
+	h.mutex.Lock()
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		entry := h.entries[i]
+		if query.Cwd != "" && !sameDir(entry.Cwd, query.Cwd) {
+			continue
+		}
+		if query.Profile != "" && entry.Profile != query.Profile {
+			continue
+		}
+		switch query.Status {
+		case "success":
+			if entry.ExitCode == nil || *entry.ExitCode != 0 {
+				continue
+			}
+		case "failure":
+			if entry.ExitCode == nil || *entry.ExitCode == 0 {
+				continue
+			}
+		}
+		if query.Unique && seen[entry.Command] {
+			continue
+		}
+		score, ok := match(entry.Command)
+		if !ok {
+			continue
+		}
+		seen[entry.Command] = true
+		results = append(results, result{entry, score})
+	}
+	h.mutex.Unlock()
+
+	if query.Mode == "fuzzy" {
+		slices.SortStableFunc(results, func(a, b result) int {
+			return b.score - a.score
+		})
+	}
+	if query.Limit > 0 && len(results) > query.Limit {
+		results = results[:query.Limit]
+	}
+	entries := make([]HistoryEntry, len(results))
+	for i, r := range results {
+		entries[i] = r.entry
+	}
+	return entries, nil
+}
+
+// fuzzyScore matches pattern as a case-insensitive subsequence of command.
+// Consecutive runs and matches at word starts score higher.
+func fuzzyScore(command string, pattern string) (int, bool) {
+	if pattern == "" {
+		return 0, true
+	}
+	text := []rune(strings.ToLower(command))
+	needle := []rune(strings.ToLower(pattern))
+	score, j, last := 0, 0, -2
+	for i := 0; i < len(text) && j < len(needle); i++ {
+		if text[i] != needle[j] {
+			continue
+		}
+		score++
+		if i == last+1 {
+			score += 2
+		}
+		if i == 0 || strings.ContainsRune(" /\\-_.", text[i-1]) {
+			score += 3
+		}
+		last = i
+		j++
+	}
+	return score - len(text)/16, j == len(needle)
+}
+
+func sameDir(a string, b string) bool {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	if filepath.Separator == '\\' {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+func (a *App) SearchHistory(query HistoryQuery) ([]HistoryEntry, error) {
+	return a.ctx.Value(HistoryKey).(*History).Search(query)
+}
+
+// RunHistoryEntry runs a past command again. A negative id opens a new
+// terminal with the entry's profile in its directory.
+func (a *App) RunHistoryEntry(entry HistoryEntry, id int) error {
+	input := entry.Command + "\r"
+	if id < 0 {
+		cwd := entry.Cwd
+		runtime.EventsEmit(a.ctx, "terminal:open", TerminalRequest{
+			Profile: entry.Profile,
+			Cwd:     &cwd,
+			Input:   &input,
+		})
+		return nil
+	}
+	term, err := a.terminal(id)
+	if err != nil {
+		return err
+	}
+	return term.send([]byte(input))
+}
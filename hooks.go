@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	hookTimeout    = time.Minute
+	startupTimeout = 10 * time.Second
+	hookOutputMax  = 1000 // bytes of a failed hook's output shown in the message
+
+	exitStatusEnv = "TERM2_EXIT_STATUS"
+)
+
+// runHook runs a preLaunch or postExit command to completion in dir, the
+// error carries the end of its output.
+func runHook(command []string, dir string, env []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	if path, ok := lookPath(command[0], env); ok {
+		cmd.Path, cmd.Err = path, nil
+	}
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.WaitDelay = time.Second
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return fmt.Errorf("%s timed out after %v", command[0], hookTimeout)
+	}
+	if err != nil {
+		message := strings.TrimSpace(string(output))
+		if len(message) > hookOutputMax {
+			message = "..." + message[len(message)-hookOutputMax:]
+		}
+		if message != "" {
+			return fmt.Errorf("%s: %w\n%s", command[0], err, message)
+		}
+		return fmt.Errorf("%s: %w", command[0], err)
+	}
+	return nil
+}
+
+// sendInput writes the startup input, once the shell is ready, and then the
+// input of the request. Ready is when the output matches pattern or, with
+// shell integration, at the first prompt. The input of the request is sent
+// even when the shell never gets ready.
+func (t *Terminal) sendInput(startup bool, pattern *regexp.Regexp, integrated bool) {
+	input := ""
+	if startup {
+		if err := t.waitReady(pattern, integrated); err != nil {
+			logger.Printf("Not sending the startup input of terminal %d: %v\n", t.id, err)
+		} else {
+			input = t.config.StartupInput
+		}
+	}
+	if t.config.Input != nil {
+		input += *t.config.Input
+	}
+	if input == "" {
+		return
+	}
+	if err := t.send([]byte(input)); err != nil {
+		logger.Println(err)
+	}
+}
+
+func (t *Terminal) waitReady(pattern *regexp.Regexp, integrated bool) error {
+	ctx, cancel := context.WithTimeout(t.ctx, startupTimeout)
+	defer cancel()
+	if pattern != nil {
+		// an offset of its own, API clients still see the whole output
+		var offset int64
+		_, err := t.expectFrom(ctx, pattern, &offset)
+		return err
+	}
+	if !integrated {
+		return nil
+	}
+	select {
+	case <-t.prompted:
+	case <-ctx.Done():
+		if t.ctx.Err() != nil {
+			return fmt.Errorf("terminal %d exited", t.id)
+		}
+		// the shell's own config may have replaced the integration's prompt
+		logger.Printf("No prompt in terminal %d after %v, sending the startup input anyway\n", t.id, startupTimeout)
+	}
+	return nil
+}
+
+// postExit runs the profile's postExit hook with the exit status of the
+// shell, empty when it is unknown.
+func (t *Terminal) postExit() {
+	t.mutex.Lock()
+	status := ""
+	if t.exitCode != nil {
+		status = strconv.Itoa(*t.exitCode)
+	}
+	dir := t.cwd
+	t.mutex.Unlock()
+	if dir == "" {
+		dir = t.cmd.Dir
+	}
+	env := append(append([]string(nil), t.cmd.Env...), exitStatusEnv+"="+status)
+	if err := runHook(t.config.PostExit, dir, env); err != nil {
+		logger.Printf("postExit of terminal %d: %v\n", t.id, err)
+	}
+}
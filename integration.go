@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+//go:embed all:shell
+var shellScripts embed.FS
+
+// installShellIntegration writes the embedded integration scripts to dir,
+// leaving files that are already up to date untouched.
+func installShellIntegration(dir string) error {
+	return fs.WalkDir(shellScripts, "shell", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, strings.TrimPrefix(path, "shell"))
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		data, err := shellScripts.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if existing, err := os.ReadFile(target); err == nil && bytes.Equal(existing, data) {
+			return nil
+		}
+		return os.WriteFile(target, data, 0644)
+	})
+}
+
+// shellKind guesses the shell from the command of a profile.
+func shellKind(command string) string {
+	name := strings.ToLower(filepath.Base(command))
+	name = strings.TrimSuffix(name, ".exe")
+	switch name {
+	case "bash", "zsh", "fish":
+		return name
+	case "pwsh", "powershell":
+		return "pwsh"
+	}
+	return ""
+}
+
+// injectShellIntegration rewrites cmd so that the shell loads the scripts in
+// dir without touching the user's dotfiles. Commands that already run a
+// script, a one-off command or a custom init file are left alone.
+func injectShellIntegration(cmd *exec.Cmd, dir string) bool {
+	args := cmd.Args[1:]
+	hasAny := func(flags ...string) bool {
+		return slices.ContainsFunc(args, func(arg string) bool {
+			return slices.ContainsFunc(flags, func(flag string) bool {
+				return strings.EqualFold(arg, flag)
+			})
+		})
+	}
+
+	env := []string{"TERM2_SHELL_INTEGRATION=1"}
+	switch shellKind(cmd.Path) {
+	case "bash":
+		if hasAny("-c", "--rcfile", "--init-file", "--norc", "--posix") || slices.ContainsFunc(args, isOperand) {
+			return false
+		}
+		if hasAny("-l", "--login") {
+			// bash ignores --rcfile in login shells, the script reads the profile files instead
+			args = slices.DeleteFunc(slices.Clone(args), func(arg string) bool {
+				return arg == "-l" || arg == "--login"
+			})
+			env = append(env, "TERM2_BASH_LOGIN=1")
+		}
+		args = append([]string{"--rcfile", filepath.Join(dir, "bash", "term2.bash")}, args...)
+	case "zsh":
+		if hasAny("-c", "-f", "--no-rcs") || slices.ContainsFunc(args, isOperand) {
+			return false
+		}
+		if zdotdir, ok := lookupEnv(cmd.Env, "ZDOTDIR"); ok {
+			env = append(env, "TERM2_ORIG_ZDOTDIR="+zdotdir)
+		}
+		env = append(env, "ZDOTDIR="+filepath.Join(dir, "zsh"))
+	case "fish":
+		if hasAny("-c", "--command", "-n", "--no-execute") || slices.ContainsFunc(args, isOperand) {
+			return false
+		}
+		args = append(slices.Clone(args), "--init-command", shellArg("source "+quotePosix(filepath.Join(dir, "fish", "term2.fish"))))
+	case "pwsh":
+		if hasAny("-c", "-command", "-f", "-file", "-e", "-ec", "-encodedcommand", "-noninteractive") {
+			return false
+		}
+		script := filepath.Join(dir, "pwsh", "term2.ps1")
+		args = append(slices.Clone(args), "-NoExit", "-Command", shellArg(". '"+strings.ReplaceAll(script, "'", "''")+"'"))
+	default:
+		return false
+	}
+
+	cmd.Args = append(cmd.Args[:1:1], args...)
+	cmd.Env = append(cmd.Env, env...)
+	return true
+}
+
+// lookupEnv finds the value of key in an environment list, the last entry wins.
+func lookupEnv(env []string, key string) (string, bool) {
+	for i := len(env) - 1; i >= 0; i-- {
+		if name, value, ok := strings.Cut(env[i], "="); ok && envNameEqual(name, key) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// isOperand reports whether arg is a script or other non-flag argument.
+func isOperand(arg string) bool {
+	return !strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "+")
+}
+
+func quotePosix(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,9 @@
+package main
+
+import "syscall"
+
+// shellArg quotes an injected argument, go-pty joins the command line
+// with plain spaces on windows.
+func shellArg(arg string) string {
+	return syscall.EscapeArg(arg)
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	jsonNull uint8 = iota
+	jsonBool
+	jsonNumber
+	jsonString
+	jsonArray
+	jsonObject
+)
+
+var jsonKindNames = []string{"null", "a boolean", "a number", "a string", "an array", "an object"}
+
+// jsonSource is a file the config was read from.
+type jsonSource struct {
+	file string
+	data []byte
+}
+
+// jsonPos is a spot in a source file, it stays valid when nodes from
+// different files are merged.
+type jsonPos struct {
+	source *jsonSource
+	offset int
+}
+
+func (p jsonPos) errorf(path string, format string, args ...any) *ConfigError {
+	err := &ConfigError{Path: path, Message: fmt.Sprintf(format, args...)}
+	if p.source != nil {
+		err.File = p.source.file
+		err.Line, err.Column = lineColumn(p.source.data, p.offset)
+	}
+	return err
+}
+
+// jsonNode is a parsed JSON value that remembers where it came from, so
+// config errors can point at the exact spot in the file.
+type jsonNode struct {
+	jsonPos
+	kind    uint8
+	bool    bool
+	number  string
+	string  string
+	items   []*jsonNode
+	members []jsonMember
+}
+
+type jsonMember struct {
+	jsonPos
+	key   string
+	value *jsonNode
+}
+
+func (n *jsonNode) member(key string) *jsonNode {
+	for _, m := range n.members {
+		if m.key == key {
+			return m.value
+		}
+	}
+	return nil
+}
+
+// jsoncParser reads JSON with // and /* */ comments and trailing commas.
+type jsoncParser struct {
+	source *jsonSource
+	data   []byte
+	pos    int
+}
+
+// parseJSONC returns a *ConfigError for syntax errors.
+func parseJSONC(source *jsonSource) (*jsonNode, error) {
+	p := &jsoncParser{source: source, data: source.data}
+	if bytes.HasPrefix(p.data, []byte("\xef\xbb\xbf")) {
+		p.pos = 3
+	}
+	node, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.skip(); err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.data) {
+		return nil, p.errorf("unexpected %s after the end of the document", p.describe())
+	}
+	return node, nil
+}
+
+func (p *jsoncParser) errorf(format string, args ...any) error {
+	return jsonPos{p.source, p.pos}.errorf("", format, args...)
+}
+
+func (p *jsoncParser) describe() string {
+	if p.pos >= len(p.data) {
+		return "end of file"
+	}
+	return strconv.QuoteRune(rune(p.data[p.pos]))
+}
+
+// skip moves past whitespace and comments.
+func (p *jsoncParser) skip() error {
+	for p.pos < len(p.data) {
+		switch c := p.data[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			p.pos++
+		case c == '/' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '/':
+			end := bytes.IndexByte(p.data[p.pos:], '\n')
+			if end == -1 {
+				p.pos = len(p.data)
+			} else {
+				p.pos += end + 1
+			}
+		case c == '/' && p.pos+1 < len(p.data) && p.data[p.pos+1] == '*':
+			end := bytes.Index(p.data[p.pos+2:], []byte("*/"))
+			if end == -1 {
+				return p.errorf("unterminated comment")
+			}
+			p.pos += end + 4
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (p *jsoncParser) value() (*jsonNode, error) {
+	if err := p.skip(); err != nil {
+		return nil, err
+	}
+	if p.pos >= len(p.data) {
+		return nil, p.errorf("unexpected end of file")
+	}
+	node := &jsonNode{jsonPos: jsonPos{p.source, p.pos}}
+	switch c := p.data[p.pos]; {
+	case c == '{':
+		node.kind = jsonObject
+		return node, p.object(node)
+	case c == '[':
+		node.kind = jsonArray
+		return node, p.array(node)
+	case c == '"':
+		node.kind = jsonString
+		s, err := p.string()
+		node.string = s
+		return node, err
+	case c == '-' || (c >= '0' && c <= '9'):
+		node.kind = jsonNumber
+		return node, p.number(node)
+	case bytes.HasPrefix(p.data[p.pos:], []byte("true")):
+		node.kind, node.bool = jsonBool, true
+		p.pos += 4
+	case bytes.HasPrefix(p.data[p.pos:], []byte("false")):
+		node.kind = jsonBool
+		p.pos += 5
+	case bytes.HasPrefix(p.data[p.pos:], []byte("null")):
+		node.kind = jsonNull
+		p.pos += 4
+	default:
+		return nil, p.errorf("unexpected %s, expected a value", p.describe())
+	}
+	return node, nil
+}
+
+func (p *jsoncParser) object(node *jsonNode) error {
+	p.pos++ // {
+	for {
+		if err := p.skip(); err != nil {
+			return err
+		}
+		if p.pos < len(p.data) && p.data[p.pos] == '}' {
+			p.pos++
+			return nil
+		}
+		if p.pos >= len(p.data) || p.data[p.pos] != '"' {
+			return p.errorf("unexpected %s, expected a key or '}'", p.describe())
+		}
+		offset := p.pos
+		key, err := p.string()
+		if err != nil {
+			return err
+		}
+		for _, m := range node.members {
+			if m.key == key {
+				p.pos = offset
+				return p.errorf("duplicate key %q", key)
+			}
+		}
+		if err := p.skip(); err != nil {
+			return err
+		}
+		if p.pos >= len(p.data) || p.data[p.pos] != ':' {
+			return p.errorf("unexpected %s, expected ':'", p.describe())
+		}
+		p.pos++
+		value, err := p.value()
+		if err != nil {
+			return err
+		}
+		node.members = append(node.members, jsonMember{jsonPos{p.source, offset}, key, value})
+		if err := p.skip(); err != nil {
+			return err
+		}
+		if p.pos < len(p.data) && p.data[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if p.pos < len(p.data) && p.data[p.pos] == '}' {
+			p.pos++
+			return nil
+		}
+		return p.errorf("unexpected %s, expected ',' or '}'", p.describe())
+	}
+}
+
+func (p *jsoncParser) array(node *jsonNode) error {
+	p.pos++ // [
+	for {
+		if err := p.skip(); err != nil {
+			return err
+		}
+		if p.pos < len(p.data) && p.data[p.pos] == ']' {
+			p.pos++
+			return nil
+		}
+		item, err := p.value()
+		if err != nil {
+			return err
+		}
+		node.items = append(node.items, item)
+		if err := p.skip(); err != nil {
+			return err
+		}
+		if p.pos < len(p.data) && p.data[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if p.pos < len(p.data) && p.data[p.pos] == ']' {
+			p.pos++
+			return nil
+		}
+		return p.errorf("unexpected %s, expected ',' or ']'", p.describe())
+	}
+}
+
+func (p *jsoncParser) string() (string, error) {
+	start := p.pos
+	p.pos++ // "
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case '\\':
+			p.pos += 2
+			continue
+		case '\n':
+			return "", p.errorf("unterminated string")
+		case '"':
+			p.pos++
+			var s string
+			// encoding/json knows all the escapes
+			if err := json.Unmarshal(p.data[start:p.pos], &s); err != nil {
+				p.pos = start
+				return "", p.errorf("%v", err)
+			}
+			return s, nil
+		}
+		p.pos++
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *jsoncParser) number(node *jsonNode) error {
+	start := p.pos
+	for p.pos < len(p.data) && strings.IndexByte("+-0123456789.eE", p.data[p.pos]) != -1 {
+		p.pos++
+	}
+	node.number = string(p.data[start:p.pos])
+	if !json.Valid(p.data[start:p.pos]) {
+		p.pos = start
+		return p.errorf("invalid number %s", node.number)
+	}
+	return nil
+}
+
+// lineColumn turns a byte offset into a 1-based line and column.
+func lineColumn(data []byte, offset int) (int, int) {
+	offset = min(offset, len(data))
+	line := bytes.Count(data[:offset], []byte{'\n'}) + 1
+	column := offset - bytes.LastIndexByte(data[:offset], '\n')
+	return line, column
+}
+
+// jsonDecoder fills Go values from nodes. Struct fields use their json tag,
+// fields tagged omitempty may be left out of the file and everything else
+// is required. Where each path was found is kept for later checks.
+type jsonDecoder struct {
+	errors    ConfigErrors
+	positions map[string]jsonPos
+}
+
+func newJSONDecoder() *jsonDecoder {
+	return &jsonDecoder{positions: make(map[string]jsonPos)}
+}
+
+func (d *jsonDecoder) errorAt(pos jsonPos, path string, format string, args ...any) {
+	d.errors = append(d.errors, pos.errorf(path, format, args...))
+}
+
+// errorPath reports an error at a path that was decoded before.
+func (d *jsonDecoder) errorPath(path string, format string, args ...any) {
+	d.errorAt(d.positions[path], path, format, args...)
+}
+
+func (d *jsonDecoder) decode(node *jsonNode, v reflect.Value, path string) {
+	d.positions[path] = node.jsonPos
+	mismatch := func(want string) {
+		d.errorAt(node.jsonPos, path, "expected %s, got %s", want, jsonKindNames[node.kind])
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if node.kind == jsonNull {
+			v.SetZero()
+			return
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		d.decode(node, v.Elem(), path)
+	case reflect.String:
+		if node.kind != jsonString {
+			mismatch("a string")
+			return
+		}
+		v.SetString(node.string)
+	case reflect.Bool:
+		if node.kind != jsonBool {
+			mismatch("a boolean")
+			return
+		}
+		v.SetBool(node.bool)
+	case reflect.Float64:
+		if node.kind != jsonNumber {
+			mismatch("a number")
+			return
+		}
+		f, _ := strconv.ParseFloat(node.number, 64)
+		v.SetFloat(f)
+	case reflect.Int:
+		if node.kind != jsonNumber {
+			mismatch("an integer")
+			return
+		}
+		i, err := strconv.Atoi(node.number)
+		if err != nil {
+			mismatch("an integer")
+			return
+		}
+		v.SetInt(int64(i))
+	case reflect.Slice:
+		if node.kind != jsonArray {
+			mismatch("an array")
+			return
+		}
+		slice := reflect.MakeSlice(v.Type(), len(node.items), len(node.items))
+		for i, item := range node.items {
+			d.decode(item, slice.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		}
+		v.Set(slice)
+	case reflect.Map:
+		if node.kind != jsonObject {
+			mismatch("an object")
+			return
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(node.members))
+		for _, member := range node.members {
+			value := reflect.New(v.Type().Elem()).Elem()
+			d.decode(member.value, value, path+"."+member.key)
+			m.SetMapIndex(reflect.ValueOf(member.key), value)
+		}
+		v.Set(m)
+	case reflect.Struct:
+		if node.kind != jsonObject {
+			mismatch("an object")
+			return
+		}
+		d.decodeStruct(node, v, path)
+	default:
+		panic("jsonDecoder: unsupported type " + v.Type().String())
+	}
+}
+
+func (d *jsonDecoder) decodeStruct(node *jsonNode, v reflect.Value, path string) {
+	if defaults, ok := v.Addr().Interface().(configDefaults); ok {
+		defaults.setDefaults()
+	}
+	known := make(map[string]bool)
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name, optional, ok := jsonField(field)
+		if !ok {
+			continue
+		}
+		known[name] = true
+		value := node.member(name)
+		if value == nil {
+			if !optional {
+				d.errorAt(node.jsonPos, path, "missing required field %q", name)
+			}
+			continue
+		}
+		d.decode(value, v.Field(i), path+"."+name)
+	}
+	for _, member := range node.members {
+		if !known[member.key] && member.key != "$schema" {
+			d.errorAt(member.jsonPos, path+"."+member.key, "unknown field %q", member.key)
+		}
+	}
+}
+
+// jsonField reads the json tag of a struct field.
+func jsonField(field reflect.StructField) (name string, optional bool, ok bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" || !field.IsExported() {
+		return "", false, false
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, strings.Contains(options, "omitempty"), true
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// plainJSON turns a node into what encoding/json makes of the same document.
+func plainJSON(t *testing.T, node *jsonNode) any {
+	t.Helper()
+	var value any
+	if err := json.Unmarshal(formatJSON(node), &value); err != nil {
+		t.Fatalf("formatJSON gave invalid JSON: %v", err)
+	}
+	return value
+}
+
+func parseTestJSON(t *testing.T, data string) any {
+	t.Helper()
+	var value any
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestParseJSONC(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`{}`, `{}`},
+		{`{"a": 1, "b": [true, false, null], "c": "x"}`, `{"a": 1, "b": [true, false, null], "c": "x"}`},
+		{"// comment\n{\"a\": 1}", `{"a": 1}`},
+		{"{\"a\": /* inline */ 1 /* more */}", `{"a": 1}`},
+		{"{\"a\": 1, // trailing\n}", `{"a": 1}`},
+		{`{"a": [1, 2,],}`, `{"a": [1, 2]}`},
+		{"\xef\xbb\xbf{\"bom\": true}", `{"bom": true}`},
+		{`{"s": "a\"b\\cé // not a comment"}`, `{"s": "a\"b\\cé // not a comment"}`},
+		{`[-1.5e3, 0, 10]`, `[-1500, 0, 10]`},
+		{"  \"top\"  ", `"top"`},
+	}
+	for _, test := range tests {
+		node, err := parseJSONC(&jsonSource{file: "test.json", data: []byte(test.in)})
+		if err != nil {
+			t.Errorf("parseJSONC(%q): %v", test.in, err)
+			continue
+		}
+		if got, want := plainJSON(t, node), parseTestJSON(t, test.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("parseJSONC(%q) = %v, want %v", test.in, got, want)
+		}
+	}
+}
+
+func TestParseJSONCErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{``, "test.json:1:1: unexpected end of file"},
+		{`{"a": 1`, "test.json:1:8: unexpected end of file, expected ',' or '}'"},
+		{"{\n  \"a\": 1,\n  \"a\": 2\n}", `test.json:3:3: duplicate key "a"`},
+		{`{"a" 1}`, "test.json:1:6: unexpected '1', expected ':'"},
+		{`{a: 1}`, "test.json:1:2: unexpected 'a', expected a key or '}'"},
+		{`[1 2]`, "test.json:1:4: unexpected '2', expected ',' or ']'"},
+		{`{"a": tru}`, "test.json:1:7: unexpected 't', expected a value"},
+		{`{"a": 01}`, "test.json:1:7: invalid number 01"},
+		{"{\"a\": \"line\nbreak\"}", "test.json:1:12: unterminated string"},
+		{`{"a": 1} /* open`, "test.json:1:10: unterminated comment"},
+		{`{} {}`, "test.json:1:4: unexpected '{' after the end of the document"},
+	}
+	for _, test := range tests {
+		_, err := parseJSONC(&jsonSource{file: "test.json", data: []byte(test.in)})
+		if err == nil || err.Error() != test.want {
+			t.Errorf("parseJSONC(%q) = %v, want %q", test.in, err, test.want)
+		}
+	}
+}
+
+type decodeTarget struct {
+	Name  string             `json:"name"`
+	Size  int                `json:"size,omitempty"`
+	Ratio float64            `json:"ratio,omitempty"`
+	On    *bool              `json:"on,omitempty"`
+	Tags  []string           `json:"tags,omitempty"`
+	Env   map[string]*string `json:"env,omitempty"`
+	Inner *decodeTarget      `json:"inner,omitempty"`
+}
+
+func TestJSONDecoder(t *testing.T) {
+	value := "v"
+	on := true
+	tests := []struct {
+		in     string
+		want   decodeTarget
+		errors []string
+	}{
+		{
+			in:   `{"name": "a", "size": 3, "ratio": 0.5, "on": true, "tags": ["x"], "env": {"K": "v", "D": null}}`,
+			want: decodeTarget{Name: "a", Size: 3, Ratio: 0.5, On: &on, Tags: []string{"x"}, Env: map[string]*string{"K": &value, "D": nil}},
+		},
+		{
+			in:   `{"$schema": "./config.schema.json", "name": "a", "inner": {"name": "b"}}`,
+			want: decodeTarget{Name: "a", Inner: &decodeTarget{Name: "b"}},
+		},
+		{
+			in:     `{}`,
+			errors: []string{`test.json:1:1: $: missing required field "name"`},
+		},
+		{
+			in: "{\n  \"name\": 1,\n  \"size\": 1.5,\n  \"extra\": true\n}",
+			errors: []string{
+				"test.json:2:11: $.name: expected a string, got a number",
+				"test.json:3:11: $.size: expected an integer, got a number",
+				`test.json:4:3: $.extra: unknown field "extra"`,
+			},
+		},
+		{
+			in: `{"name": "a", "tags": "x", "env": {"K": 1}, "inner": {}}`,
+			errors: []string{
+				"test.json:1:23: $.tags: expected an array, got a string",
+				"test.json:1:41: $.env.K: expected a string, got a number",
+				`test.json:1:54: $.inner: missing required field "name"`,
+			},
+		},
+	}
+	for _, test := range tests {
+		node, err := parseJSONC(&jsonSource{file: "test.json", data: []byte(test.in)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got decodeTarget
+		decoder := newJSONDecoder()
+		decoder.decode(node, reflect.ValueOf(&got).Elem(), "$")
+		var errors []string
+		for _, err := range decoder.errors {
+			errors = append(errors, err.Error())
+		}
+		if !reflect.DeepEqual(errors, test.errors) {
+			t.Errorf("decoding %q: errors %q, want %q", test.in, errors, test.errors)
+			continue
+		}
+		if test.errors == nil && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("decoding %q = %+v, want %+v", test.in, got, test.want)
+		}
+	}
+}
+
+func TestLineColumn(t *testing.T) {
+	data := []byte("ab\ncd\n\nef")
+	tests := []struct {
+		offset, line, column int
+	}{
+		{0, 1, 1},
+		{1, 1, 2},
+		{3, 2, 1},
+		{6, 3, 1},
+		{8, 4, 2},
+		{100, 4, 3},
+	}
+	for _, test := range tests {
+		line, column := lineColumn(data, test.offset)
+		if line != test.line || column != test.column {
+			t.Errorf("lineColumn(%d) = %d:%d, want %d:%d", test.offset, line, column, test.line, test.column)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"path/filepath"
+
+	"github.com/wailsapp/wails/v2/pkg/options"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// launchArgs are the command line arguments of term2.
+type launchArgs struct {
+	configDir string
+	portable  bool
+	profile   string
+	cwd       string
+	title     string
+	workspace string
+	command   []string // everything after -e
+	rest      []string // dev or config <command>
+}
+
+func parseArgs(args []string, output io.Writer) (*launchArgs, error) {
+	parsed := &launchArgs{}
+	flags := flag.NewFlagSet("term2", flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.StringVar(&parsed.configDir, "config-dir", "", "directory for the config, assets, certs and app data")
+	flags.BoolVar(&parsed.portable, "portable", false, "keep everything next to the executable")
+	flags.StringVar(&parsed.profile, "profile", "", "profile of the new tab")
+	flags.StringVar(&parsed.cwd, "cwd", "", "working directory of the new tab")
+	flags.StringVar(&parsed.title, "title", "", "fixed title of the new tab")
+	flags.StringVar(&parsed.workspace, "workspace", "", "open the tabs of a workspace, a name, a file or a directory with a "+workspaceFile)
+	execute := flags.Bool("e", false, "run the rest of the arguments instead of the profile's command")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if *execute {
+		parsed.command = flags.Args()
+	} else {
+		parsed.rest = flags.Args()
+	}
+	return parsed, nil
+}
+
+// request turns the options for a tab into a request for the frontend, nil
+// when there are none. A relative --cwd is resolved against dir, which is
+// also the default when it isn't empty.
+func (l *launchArgs) request(dir string) *TerminalRequest {
+	cwd := l.cwd
+	if cwd != "" && !filepath.IsAbs(cwd) && dir != "" {
+		cwd = filepath.Join(dir, cwd)
+	}
+	if cwd == "" {
+		cwd = dir
+	}
+	if l.profile == "" && cwd == "" && l.title == "" && len(l.command) == 0 {
+		return nil
+	}
+
+	request := &TerminalRequest{Profile: l.profile, Title: l.title, Command: l.command}
+	if cwd != "" {
+		request.Cwd = &cwd
+	}
+	return request
+}
+
+// launchRequests returns the tabs the command line asks for, a workspace or a
+// single tab, nil when there are no options.
+func (a *App) launchRequests(l *launchArgs, dir string) ([]TerminalRequest, error) {
+	if l.workspace != "" {
+		return a.loadWorkspace(l.workspace, dir)
+	}
+	if request := l.request(dir); request != nil {
+		return []TerminalRequest{*request}, nil
+	}
+	return nil, nil
+}
+
+// GetLaunchRequests returns the tabs the command line asked for once, none
+// when the default profile should be opened.
+func (a *App) GetLaunchRequests() ([]TerminalRequest, error) {
+	a.configMutex.Lock()
+	launch := a.launch
+	a.launch = nil
+	a.configMutex.Unlock()
+	if launch == nil {
+		return nil, nil
+	}
+	return a.launchRequests(launch, "")
+}
+
+// onSecondInstanceLaunch opens a tab for a term2 started while this one was
+// running, in the directory it was started from.
+func (a *App) onSecondInstanceLaunch(data options.SecondInstanceData) {
+	runtime.WindowUnminimise(a.ctx)
+	runtime.Show(a.ctx)
+
+	args, err := parseArgs(data.Args, io.Discard)
+	if err != nil {
+		logger.Println(err)
+		return
+	}
+	requests, err := a.launchRequests(args, data.WorkingDirectory)
+	if err != nil {
+		logger.Println(err)
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:    runtime.ErrorDialog,
+			Title:   "Error",
+			Message: "Couldn't open workspace: " + err.Error(),
+		})
+		return
+	}
+	if len(requests) == 0 {
+		requests = []TerminalRequest{{}}
+	}
+	runtime.EventsEmit(a.ctx, "terminals:open", requests)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultLoginShellTimeout = 5 // seconds
+
+const loginEnvMarker = "__TERM2_LOGIN_ENV__"
+
+// variables that only describe the capturing shell itself
+var loginEnvIgnored = []string{"_", "PWD", "OLDPWD", "SHLVL"}
+
+// LoginShellEnv runs the user's login shell once and uses its environment
+// as the base for every terminal, for launches from a desktop session that
+// never read .profile.
+type LoginShellEnv struct {
+	Enabled bool    `json:"enabled"`
+	Shell   string  `json:"shell,omitempty"`   // $SHELL when empty
+	Timeout float64 `json:"timeout,omitempty"` // seconds
+}
+
+func (l *LoginShellEnv) setDefaults() {
+	l.Timeout = defaultLoginShellTimeout
+}
+
+// LoginEnvReport describes where the base environment came from and how it
+// differs from the one term2 was started with.
+type LoginEnvReport struct {
+	Shell    string   `json:"shell"`
+	Source   string   `json:"source"` // disabled, pending, login-shell, cache or inherited
+	Error    string   `json:"error,omitempty"`
+	Duration float64  `json:"duration"` // seconds
+	Added    []string `json:"added"`    // KEY=value
+	Changed  []string `json:"changed"`  // KEY=value as the login shell set it
+}
+
+type loginEnvCache struct {
+	Shell string   `json:"shell"`
+	Env   []string `json:"env"`
+}
+
+// loginEnv holds the captured environment, a capture runs again whenever
+// the settings change.
+type loginEnv struct {
+	mutex    sync.Mutex
+	settings *LoginShellEnv
+	done     chan struct{}
+	env      []string
+	report   LoginEnvReport
+}
+
+func (l *loginEnv) configure(settings *LoginShellEnv, cacheFile string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.done != nil && reflect.DeepEqual(settings, l.settings) {
+		return
+	}
+	l.settings = settings
+	l.env = nil
+	done := make(chan struct{})
+	l.done = done
+
+	switch {
+	case settings == nil || !settings.Enabled:
+		l.report = LoginEnvReport{Source: "disabled", Added: []string{}, Changed: []string{}}
+		close(done)
+	case runtime.GOOS == "windows":
+		l.report = LoginEnvReport{Source: "inherited", Error: "login shells are not supported on windows", Added: []string{}, Changed: []string{}}
+		close(done)
+	default:
+		l.report = LoginEnvReport{Source: "pending", Added: []string{}, Changed: []string{}}
+		go l.capture(*settings, cacheFile, done)
+	}
+}
+
+func (l *loginEnv) capture(settings LoginShellEnv, cacheFile string, done chan struct{}) {
+	defer close(done)
+
+	shell := settings.Shell
+	if shell == "" {
+		shell = os.Getenv("SHELL")
+	}
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	report := LoginEnvReport{Shell: shell, Source: "login-shell"}
+
+	start := time.Now()
+	env, err := captureLoginEnv(shell, time.Duration(settings.Timeout*float64(time.Second)))
+	report.Duration = time.Since(start).Seconds()
+	if err == nil {
+		if data, err := json.Marshal(loginEnvCache{shell, env}); err == nil {
+			err = os.MkdirAll(filepath.Dir(cacheFile), 0755)
+			if err == nil {
+				// replaced rather than rewritten so older caches lose their 0644 too
+				err = writeFileAtomic(cacheFile, data, 0600)
+			}
+			if err != nil {
+				logger.Println(err)
+			}
+		}
+	} else {
+		report.Error = err.Error()
+		report.Source = "inherited"
+		var cache loginEnvCache
+		if data, readErr := os.ReadFile(cacheFile); readErr == nil && json.Unmarshal(data, &cache) == nil && cache.Shell == shell {
+			env = cache.Env
+			report.Source = "cache"
+		}
+	}
+
+	base := os.Environ()
+	report.Added, report.Changed = []string{}, []string{}
+	for _, entry := range env {
+		name, value, _ := strings.Cut(entry, "=")
+		current, ok := lookupEnv(base, name)
+		if !ok {
+			report.Added = append(report.Added, entry)
+		} else if current != value {
+			report.Changed = append(report.Changed, entry)
+		}
+		base = setEnv(base, name, value)
+	}
+	if report.Error != "" {
+		logger.Println(report.Error)
+	}
+	logger.Printf("Login shell environment of %s from %s in %.2fs: %d added, %d changed\n", shell, report.Source, report.Duration, len(report.Added), len(report.Changed))
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.done != done {
+		return
+	}
+	if env != nil {
+		l.env = base
+	}
+	l.report = report
+}
+
+// captureLoginEnv runs shell as a login shell and reads its environment,
+// markers separate it from whatever the profile files print.
+func captureLoginEnv(shell string, timeout time.Duration) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	script := fmt.Sprintf("printf %s; command env -0; printf %s", loginEnvMarker, loginEnvMarker)
+	cmd := exec.CommandContext(ctx, shell, "-l", "-c", script)
+	cmd.WaitDelay = time.Second
+	output, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("%s timed out after %v", shell, timeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", shell, err)
+	}
+
+	_, output, found := bytes.Cut(output, []byte(loginEnvMarker))
+	if found {
+		output, _, found = bytes.Cut(output, []byte(loginEnvMarker))
+	}
+	if !found {
+		return nil, errors.New("couldn't find the environment in the output of " + shell)
+	}
+	var env []string
+	for _, entry := range strings.Split(string(output), "\x00") {
+		name, _, ok := strings.Cut(entry, "=")
+		if !ok || name == "" || slices.Contains(loginEnvIgnored, name) {
+			continue
+		}
+		env = append(env, entry)
+	}
+	return env, nil
+}
+
+// environ returns the base environment for new terminals, waiting for a
+// capture that is still running.
+func (l *loginEnv) environ() []string {
+	l.mutex.Lock()
+	done := l.done
+	l.mutex.Unlock()
+	if done != nil {
+		<-done
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.env == nil {
+		return os.Environ()
+	}
+	return slices.Clone(l.env)
+}
+
+func (a *App) loginEnvCacheFile() string {
+	return filepath.Join(a.paths.Cache, "login-env.json")
+}
+
+// GetLoginEnvReport returns how the base environment of new terminals was
+// resolved.
+func (a *App) GetLoginEnvReport() LoginEnvReport {
+	a.loginEnv.mutex.Lock()
+	defer a.loginEnv.mutex.Unlock()
+	return a.loginEnv.report
+}
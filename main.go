@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"embed"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -18,7 +19,6 @@ import (
 	"github.com/wailsapp/wails/v2/pkg/options/linux"
 	"github.com/wailsapp/wails/v2/pkg/options/mac"
 	"github.com/wailsapp/wails/v2/pkg/options/windows"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 //go:embed all:frontend/dist
@@ -41,29 +41,7 @@ func myFileHandler(next http.Handler) http.Handler {
 		}
 
 		if strings.HasPrefix(requestedFilename, ".") {
-			if app.dev {
-				cwd, err := os.Getwd()
-				if err != nil {
-					runtime.MessageDialog(app.ctx, runtime.MessageDialogOptions{
-						Type:    runtime.ErrorDialog,
-						Title:   "Error",
-						Message: "Couldn't find CWD",
-					})
-					runtime.Quit(app.ctx)
-				}
-				requestedFilename = filepath.Join(cwd, "assets", requestedFilename)
-			} else {
-				basePath, err := os.UserHomeDir()
-				if err != nil {
-					runtime.MessageDialog(app.ctx, runtime.MessageDialogOptions{
-						Type:    runtime.ErrorDialog,
-						Title:   "Error",
-						Message: "Couldn't find HOMEDIR",
-					})
-					runtime.Quit(app.ctx)
-				}
-				requestedFilename = filepath.Join(basePath, "/.term2/assets", requestedFilename)
-			}
+			requestedFilename = filepath.Join(app.paths.Assets(), requestedFilename)
 		}
 		logger.Printf("Requested file: %s\n", requestedFilename)
 
@@ -100,10 +78,46 @@ func main() {
 	}
 	logger = log.New(logFile, "App ", log.Lshortfile|log.Lmsgprefix|log.Ltime)
 
-	flag.Parse()
-	dev := flag.Arg(0) == "dev"
+	args, err := parseArgs(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		os.Exit(2)
+	}
+	if len(args.rest) > 0 && args.rest[0] == "@" {
+		attachConsole()
+		os.Exit(runRemoteCommand(args.rest[1:]))
+	}
+	dev := len(args.rest) > 0 && args.rest[0] == "dev"
+	paths, err := resolvePaths(args.configDir, args.portable, dev)
+	if err != nil {
+		logger.Println(err)
+		println("Error:", err.Error())
+		os.Exit(1)
+	}
+	// the temp file only has what happened before the paths were known
+	if err := os.MkdirAll(paths.State, 0755); err != nil {
+		logger.Println(err)
+	} else if file, err := os.OpenFile(filepath.Join(paths.State, "term2.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600); err != nil {
+		logger.Println(err)
+	} else {
+		logFile.Close()
+		logFile = file
+		logger.SetOutput(logFile)
+	}
+	if len(args.rest) > 0 && args.rest[0] == "config" {
+		attachConsole()
+		os.Exit(runConfigCommand(paths, args.rest[1:]))
+	}
+	if args.cwd != "" {
+		if args.cwd, err = filepath.Abs(args.cwd); err != nil {
+			logger.Println(err)
+		}
+	}
+	logger.Printf("Using config %s, state %s and cache %s\n", paths.Config, paths.State, paths.Cache)
 	// Create an instance of the app structure
-	app := NewApp(dev)
+	app := NewApp(dev, paths)
+	app.launch = args
 
 	appName := "term2"
 
@@ -135,11 +149,8 @@ func main() {
 			},
 		},
 		SingleInstanceLock: &options.SingleInstanceLock{
-			UniqueId: "fToKHxo3iuaCiSNhBB8EELCsvy03EvV5npJur7neMFc",
-			OnSecondInstanceLaunch: func(secondInstanceData options.SecondInstanceData) {
-				runtime.WindowUnminimise(app.ctx)
-				runtime.Show(app.ctx)
-			},
+			UniqueId:               "fToKHxo3iuaCiSNhBB8EELCsvy03EvV5npJur7neMFc",
+			OnSecondInstanceLaunch: app.onSecondInstanceLaunch,
 		},
 		BackgroundColour: &options.RGBA{R: 27, G: 38, B: 54, A: 1},
 		OnStartup:        app.startup,
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Merge rules, used for includes, profileDefaults and extends:
+//   - objects are merged key by key, recursively
+//   - profiles and fonts are matched by name, a later entry with the same
+//     name is merged into the earlier one and keeps its position
+//   - shortcuts are concatenated
+//   - everything else, including other arrays, is replaced by the later value
+//
+// A file's includes are merged in order before the file itself, so the
+// including file always wins. A profile is built from profileDefaults, then
+// the profile it extends and then its own fields. name and shortcut are
+// never inherited.
+
+// configLoader reads a config file with everything it includes.
+type configLoader struct {
+	files  []string
+	stack  []string
+	errors ConfigErrors
+}
+
+func (l *configLoader) load(file string, data []byte, from jsonPos) *jsonNode {
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	if i := slices.Index(l.stack, file); i != -1 {
+		chain := append(slices.Clone(l.stack[i:]), file)
+		l.errors = append(l.errors, from.errorf("$.include", "include cycle: %s", strings.Join(chain, " -> ")))
+		return nil
+	}
+	if !slices.Contains(l.files, file) {
+		l.files = append(l.files, file)
+	}
+	if data == nil {
+		var err error
+		data, err = os.ReadFile(file)
+		if err != nil {
+			l.errors = append(l.errors, from.errorf("$.include", "couldn't read include: %v", err))
+			return nil
+		}
+	}
+
+	root, err := parseJSONC(&jsonSource{file: file, data: data})
+	if err != nil {
+		l.errors = append(l.errors, err.(*ConfigError))
+		return nil
+	}
+	if root.kind != jsonObject {
+		l.errors = append(l.errors, root.errorf("$", "expected an object, got %s", jsonKindNames[root.kind]))
+		return nil
+	}
+
+	l.stack = append(l.stack, file)
+	defer func() {
+		l.stack = l.stack[:len(l.stack)-1]
+	}()
+
+	var merged *jsonNode
+	for i, member := range root.members {
+		if member.key != "include" {
+			continue
+		}
+		var includes []*jsonNode
+		switch member.value.kind {
+		case jsonString:
+			includes = []*jsonNode{member.value}
+		case jsonArray:
+			includes = member.value.items
+		default:
+			l.errors = append(l.errors, member.value.errorf("$.include", "expected a string or an array of strings"))
+		}
+		for _, include := range includes {
+			if include.kind != jsonString {
+				l.errors = append(l.errors, include.errorf("$.include", "expected a string, got %s", jsonKindNames[include.kind]))
+				continue
+			}
+			path := include.string
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(filepath.Dir(file), path)
+			}
+			if node := l.load(path, nil, include.jsonPos); node != nil {
+				merged = mergeConfig(merged, node)
+			}
+		}
+		root = withoutMember(root, i)
+		break
+	}
+	return mergeConfig(merged, root)
+}
+
+// mergeConfig merges two config documents.
+func mergeConfig(base *jsonNode, over *jsonNode) *jsonNode {
+	if base == nil || base.kind != jsonObject || over.kind != jsonObject {
+		return over
+	}
+	merged := *over
+	merged.members = slices.Clone(base.members)
+	for _, member := range over.members {
+		i := slices.IndexFunc(merged.members, func(m jsonMember) bool {
+			return m.key == member.key
+		})
+		if i == -1 {
+			merged.members = append(merged.members, member)
+			continue
+		}
+		switch member.key {
+		case "profiles", "fonts":
+			member.value = mergeNamed(merged.members[i].value, member.value)
+		case "shortcuts":
+			member.value = concatArrays(merged.members[i].value, member.value)
+		default:
+			member.value = mergeNodes(merged.members[i].value, member.value)
+		}
+		merged.members[i] = member
+	}
+	return &merged
+}
+
+// mergeNodes merges objects key by key, anything else in over replaces base.
+func mergeNodes(base *jsonNode, over *jsonNode) *jsonNode {
+	if base == nil || base.kind != jsonObject || over.kind != jsonObject {
+		return over
+	}
+	merged := *over
+	merged.members = slices.Clone(base.members)
+	for _, member := range over.members {
+		i := slices.IndexFunc(merged.members, func(m jsonMember) bool {
+			return m.key == member.key
+		})
+		if i == -1 {
+			merged.members = append(merged.members, member)
+			continue
+		}
+		member.value = mergeNodes(merged.members[i].value, member.value)
+		merged.members[i] = member
+	}
+	return &merged
+}
+
+// mergeNamed merges arrays of objects by their name field.
+func mergeNamed(base *jsonNode, over *jsonNode) *jsonNode {
+	if base.kind != jsonArray || over.kind != jsonArray {
+		return over
+	}
+	merged := *over
+	merged.items = slices.Clone(base.items)
+	for _, item := range over.items {
+		name, ok := nodeName(item)
+		i := slices.IndexFunc(merged.items, func(n *jsonNode) bool {
+			other, otherOk := nodeName(n)
+			return ok && otherOk && name == other
+		})
+		if i == -1 {
+			merged.items = append(merged.items, item)
+		} else {
+			merged.items[i] = mergeNodes(merged.items[i], item)
+		}
+	}
+	return &merged
+}
+
+func concatArrays(base *jsonNode, over *jsonNode) *jsonNode {
+	if base.kind != jsonArray || over.kind != jsonArray {
+		return over
+	}
+	merged := *over
+	merged.items = append(slices.Clone(base.items), over.items...)
+	return &merged
+}
+
+func nodeName(node *jsonNode) (string, bool) {
+	if node.kind != jsonObject {
+		return "", false
+	}
+	name := node.member("name")
+	if name == nil || name.kind != jsonString {
+		return "", false
+	}
+	return name.string, true
+}
+
+func withoutMember(node *jsonNode, i int) *jsonNode {
+	copied := *node
+	copied.members = slices.Delete(slices.Clone(node.members), i, i+1)
+	return &copied
+}
+
+func withoutKeys(node *jsonNode, keys ...string) *jsonNode {
+	if node == nil || node.kind != jsonObject {
+		return node
+	}
+	copied := *node
+	copied.members = slices.DeleteFunc(slices.Clone(node.members), func(m jsonMember) bool {
+		return slices.Contains(keys, m.key)
+	})
+	return &copied
+}
+
+// resolveProfiles applies profileDefaults and extends to every profile and
+// removes both keys from the document.
+func (l *configLoader) resolveProfiles(root *jsonNode) *jsonNode {
+	var defaults *jsonNode
+	if i := slices.IndexFunc(root.members, func(m jsonMember) bool { return m.key == "profileDefaults" }); i != -1 {
+		defaults = root.members[i].value
+		root = withoutMember(root, i)
+		if defaults.kind != jsonObject {
+			l.errors = append(l.errors, defaults.errorf("$.profileDefaults", "expected an object, got %s", jsonKindNames[defaults.kind]))
+			defaults = nil
+		}
+	}
+	defaults = withoutKeys(defaults, "name", "shortcut", "extends")
+
+	i := slices.IndexFunc(root.members, func(m jsonMember) bool { return m.key == "profiles" })
+	if i == -1 || root.members[i].value.kind != jsonArray {
+		return root
+	}
+	profiles := *root.members[i].value
+	profiles.items = slices.Clone(profiles.items)
+
+	byName := make(map[string]*jsonNode)
+	index := make(map[*jsonNode]int)
+	for j, item := range profiles.items {
+		if name, ok := nodeName(item); ok {
+			byName[name] = item
+		}
+		index[item] = j
+	}
+	resolved := make(map[*jsonNode]*jsonNode)
+	var resolve func(item *jsonNode, chain []string) *jsonNode
+	resolve = func(item *jsonNode, chain []string) *jsonNode {
+		if result, ok := resolved[item]; ok {
+			return result
+		}
+		name, _ := nodeName(item)
+		base := defaults
+		if extends := item.member("extends"); extends != nil && item.kind == jsonObject {
+			path := fmt.Sprintf("$.profiles[%d].extends", index[item])
+			switch {
+			case extends.kind != jsonString:
+				l.errors = append(l.errors, extends.errorf(path, "expected a string, got %s", jsonKindNames[extends.kind]))
+			case byName[extends.string] == nil:
+				l.errors = append(l.errors, extends.errorf(path, "no profile named %q", extends.string))
+			case slices.Contains(chain, extends.string) || extends.string == name:
+				cycle := append(slices.Clone(chain), name, extends.string)
+				cycle = cycle[slices.Index(cycle, extends.string):]
+				l.errors = append(l.errors, extends.errorf(path, "extends cycle: %s", strings.Join(cycle, " -> ")))
+			default:
+				parent := resolve(byName[extends.string], append(chain, name))
+				base = mergeNodes(defaults, withoutKeys(parent, "name", "shortcut"))
+			}
+		}
+		result := mergeNodes(base, withoutKeys(item, "extends"))
+		resolved[item] = result
+		return result
+	}
+	for j, item := range profiles.items {
+		profiles.items[j] = resolve(item, nil)
+	}
+
+	copied := *root
+	copied.members = slices.Clone(root.members)
+	copied.members[i].value = &profiles
+	return &copied
+}
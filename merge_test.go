@@ -0,0 +1,186 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func mustParseJSONC(t *testing.T, data string) *jsonNode {
+	t.Helper()
+	node, err := parseJSONC(&jsonSource{file: "test.json", data: []byte(data)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return node
+}
+
+func TestMergeConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		base, over string
+		want       string
+	}{
+		{
+			name: "objects by key",
+			base: `{"a": 1, "theme": {"background": "black", "foreground": "white"}}`,
+			over: `{"b": 2, "theme": {"background": "blue"}}`,
+			want: `{"a": 1, "b": 2, "theme": {"background": "blue", "foreground": "white"}}`,
+		},
+		{
+			name: "profiles by name",
+			base: `{"profiles": [{"name": "bash", "command": "bash", "fontSize": 14}, {"name": "zsh"}]}`,
+			over: `{"profiles": [{"name": "fish"}, {"name": "bash", "fontSize": 18}]}`,
+			want: `{"profiles": [{"name": "bash", "command": "bash", "fontSize": 18}, {"name": "zsh"}, {"name": "fish"}]}`,
+		},
+		{
+			name: "fonts by name",
+			base: `{"fonts": [{"name": "a", "url": "a.ttf"}]}`,
+			over: `{"fonts": [{"name": "a", "url": "b.ttf"}]}`,
+			want: `{"fonts": [{"name": "a", "url": "b.ttf"}]}`,
+		},
+		{
+			name: "shortcuts appended",
+			base: `{"shortcuts": [{"action": "copy"}]}`,
+			over: `{"shortcuts": [{"action": "paste"}]}`,
+			want: `{"shortcuts": [{"action": "copy"}, {"action": "paste"}]}`,
+		},
+		{
+			name: "other arrays replaced",
+			base: `{"profiles": [{"name": "a", "args": ["-l", "-i"]}]}`,
+			over: `{"profiles": [{"name": "a", "args": ["-c"]}]}`,
+			want: `{"profiles": [{"name": "a", "args": ["-c"]}]}`,
+		},
+		{
+			name: "different kinds replaced",
+			base: `{"include": ["a.json"], "theme": {"background": "black"}}`,
+			over: `{"include": "b.json", "theme": null}`,
+			want: `{"include": "b.json", "theme": null}`,
+		},
+	}
+	for _, test := range tests {
+		merged := mergeConfig(mustParseJSONC(t, test.base), mustParseJSONC(t, test.over))
+		if got, want := plainJSON(t, merged), parseTestJSON(t, test.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, want)
+		}
+	}
+}
+
+func TestResolveProfiles(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+		err  string
+	}{
+		{
+			name: "defaults",
+			in:   `{"profileDefaults": {"fontSize": 14, "name": "x", "shortcut": {"code": "KeyA"}}, "profiles": [{"name": "a"}, {"name": "b", "fontSize": 18}]}`,
+			want: `{"profiles": [{"fontSize": 14, "name": "a"}, {"fontSize": 18, "name": "b"}]}`,
+		},
+		{
+			name: "extends",
+			in: `{"profileDefaults": {"fontSize": 14, "theme": {"background": "black"}}, "profiles": [
+				{"name": "child", "extends": "parent", "theme": {"foreground": "white"}},
+				{"name": "parent", "command": "bash", "fontSize": 16, "shortcut": {"code": "Digit1"}}
+			]}`,
+			want: `{"profiles": [
+				{"name": "child", "command": "bash", "fontSize": 16, "theme": {"background": "black", "foreground": "white"}},
+				{"name": "parent", "command": "bash", "fontSize": 16, "shortcut": {"code": "Digit1"}, "theme": {"background": "black"}}
+			]}`,
+		},
+		{
+			name: "extends chain",
+			in:   `{"profiles": [{"name": "a", "command": "sh"}, {"name": "b", "extends": "a", "args": ["-l"]}, {"name": "c", "extends": "b"}]}`,
+			want: `{"profiles": [{"name": "a", "command": "sh"}, {"name": "b", "command": "sh", "args": ["-l"]}, {"name": "c", "command": "sh", "args": ["-l"]}]}`,
+		},
+		{
+			name: "unknown parent",
+			in:   `{"profiles": [{"name": "a", "extends": "missing"}]}`,
+			err:  `$.profiles[0].extends: no profile named "missing"`,
+		},
+		{
+			name: "cycle",
+			in:   `{"profiles": [{"name": "a", "extends": "b"}, {"name": "b", "extends": "a"}]}`,
+			err:  "extends cycle: a -> b -> a",
+		},
+		{
+			name: "extends itself",
+			in:   `{"profiles": [{"name": "a", "extends": "a"}]}`,
+			err:  "extends cycle: a -> a",
+		},
+		{
+			name: "defaults not an object",
+			in:   `{"profileDefaults": [], "profiles": []}`,
+			err:  "$.profileDefaults: expected an object, got an array",
+		},
+	}
+	for _, test := range tests {
+		loader := &configLoader{}
+		resolved := loader.resolveProfiles(mustParseJSONC(t, test.in))
+		if test.err != "" {
+			if len(loader.errors) == 0 || !strings.Contains(loader.errors.Error(), test.err) {
+				t.Errorf("%s: errors %v, want %q", test.name, loader.errors, test.err)
+			}
+			continue
+		}
+		if len(loader.errors) > 0 {
+			t.Errorf("%s: %v", test.name, loader.errors)
+			continue
+		}
+		if got, want := plainJSON(t, resolved), parseTestJSON(t, test.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, want)
+		}
+	}
+}
+
+func TestConfigLoaderIncludes(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"base.json":       `{"defaultProfile": "a", "profiles": [{"name": "a", "fontSize": 12}]}`,
+		"sub/colors.json": `{"include": "../base.json", "profiles": [{"name": "a", "theme": {"background": "black"}}]}`,
+		"cycle.json":      `{"include": "cycle.json"}`,
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loader := &configLoader{}
+	root := loader.load(filepath.Join(dir, "config.json"), []byte(`{"include": ["sub/colors.json"], "profiles": [{"name": "a", "fontSize": 18}]}`), jsonPos{})
+	if len(loader.errors) > 0 {
+		t.Fatal(loader.errors)
+	}
+	want := `{"defaultProfile": "a", "profiles": [{"name": "a", "fontSize": 18, "theme": {"background": "black"}}]}`
+	if got := plainJSON(t, root); !reflect.DeepEqual(got, parseTestJSON(t, want)) {
+		t.Errorf("got %v, want %s", got, want)
+	}
+	if len(loader.files) != 3 {
+		t.Errorf("files %q, want config.json, colors.json and base.json", loader.files)
+	}
+
+	tests := []struct {
+		data string
+		err  string
+	}{
+		{`{"include": "cycle.json"}`, "include cycle: "},
+		{`{"include": "missing.json"}`, "couldn't read include"},
+		{`{"include": 1}`, "expected a string or an array of strings"},
+		{`{"include": [1]}`, "expected a string, got a number"},
+		{`[]`, "$: expected an object, got an array"},
+	}
+	for _, test := range tests {
+		loader := &configLoader{}
+		loader.load(filepath.Join(dir, "config.json"), []byte(test.data), jsonPos{})
+		if len(loader.errors) == 0 || !strings.Contains(loader.errors.Error(), test.err) {
+			t.Errorf("load(%s): errors %v, want %q", test.data, loader.errors, test.err)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// configVersion is the version of the config format, a config without a
+// version is version 1.
+const configVersion = 2
+
+// configMigrations upgrade a config document by one version, the first one
+// goes from version 1 to 2. report describes every change.
+var configMigrations = []func(root *jsonNode, report func(format string, args ...any)) *jsonNode{
+	migrateToV2,
+}
+
+// actionsSinceV1 got default bindings after version 1.
+var actionsSinceV1 = []string{"newTerminalHere", "previousPrompt", "nextPrompt", "copyLastCommandOutput", "reopenClosedTerminal"}
+
+const placeholderProfile = "create your own profile here"
+
+// configFileVersion reads the version of a config document.
+func configFileVersion(root *jsonNode) (int, error) {
+	node := root.member("version")
+	if node == nil {
+		return 1, nil
+	}
+	version, err := strconv.Atoi(node.number)
+	if node.kind != jsonNumber || err != nil || version < 1 {
+		return 0, node.errorf("$.version", "expected a positive integer")
+	}
+	if version > configVersion {
+		return 0, node.errorf("$.version", "version %d is newer than this term2 understands (%d)", version, configVersion)
+	}
+	return version, nil
+}
+
+// migrateConfig upgrades a config document to the current version.
+func migrateConfig(root *jsonNode) (*jsonNode, []string, error) {
+	version, err := configFileVersion(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	changes := []string{}
+	report := func(format string, args ...any) {
+		changes = append(changes, fmt.Sprintf(format, args...))
+	}
+	for ; version < configVersion; version++ {
+		root = configMigrations[version-1](root, report)
+	}
+	if len(changes) > 0 || root.member("version") == nil {
+		root = setMember(root, "version", &jsonNode{kind: jsonNumber, number: strconv.Itoa(configVersion)})
+		report("set version to %d", configVersion)
+	}
+	return root, changes, nil
+}
+
+func migrateToV2(root *jsonNode, report func(format string, args ...any)) *jsonNode {
+	// the frontend used to drop fields it didn't know, the app rejects them now
+	root = stripUnknown(root, reflect.TypeFor[Config](), "$", report)
+
+	defaults, err := parseJSONC(&jsonSource{file: "default", data: []byte(DefaultKeybinds)})
+	if err != nil {
+		panic(err)
+	}
+
+	// the old default config came with a placeholder profile
+	if profiles := root.member("profiles"); profiles != nil && profiles.kind == jsonArray {
+		for i, profile := range profiles.items {
+			if name, _ := nodeName(profile); name == placeholderProfile && profile.member("command") == nil {
+				replacement := defaults.member("profiles").items[0]
+				profiles.items[i] = replacement
+				report("replaced the placeholder profile with %q", replacement.member("name").string)
+			}
+		}
+	}
+
+	shortcuts := root.member("shortcuts")
+	if shortcuts != nil && shortcuts.kind == jsonArray {
+		bound := func(binding *jsonNode) bool {
+			action := binding.member("action")
+			return slices.ContainsFunc(shortcuts.items, func(existing *jsonNode) bool {
+				other := existing.member("action")
+				return (other != nil && other.string == action.string) ||
+					sameShortcut(existing.member("shortcut"), binding.member("shortcut"))
+			})
+		}
+		for _, binding := range defaults.member("shortcuts").items {
+			action := binding.member("action").string
+			if !slices.Contains(actionsSinceV1, action) || bound(binding) {
+				continue
+			}
+			shortcuts.items = append(shortcuts.items, binding)
+			report("added a binding for %s", action)
+		}
+	}
+
+	if root.member("$schema") == nil {
+		root.members = slices.Insert(root.members, 0, jsonMember{key: "$schema", value: &jsonNode{kind: jsonString, string: "./" + schemaFile}})
+		report("added $schema")
+	}
+	return root
+}
+
+func sameShortcut(a *jsonNode, b *jsonNode) bool {
+	if a == nil || b == nil || a.kind != jsonObject || b.kind != jsonObject {
+		return false
+	}
+	value := func(node *jsonNode, key string) string {
+		member := node.member(key)
+		switch {
+		case member == nil && key == "type":
+			return "keydown"
+		case member == nil:
+			return ""
+		case member.kind == jsonBool && member.bool:
+			return "true"
+		}
+		return member.string
+	}
+	for _, key := range []string{"code", "type", "ctrlKey", "shiftKey", "altKey"} {
+		if value(a, key) != value(b, key) {
+			return false
+		}
+	}
+	return true
+}
+
+// stripUnknown removes the fields the decoder would reject.
+func stripUnknown(node *jsonNode, t reflect.Type, path string, report func(format string, args ...any)) *jsonNode {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	copied := *node
+	switch {
+	case t.Kind() == reflect.Slice && node.kind == jsonArray:
+		copied.items = make([]*jsonNode, len(node.items))
+		for i, item := range node.items {
+			copied.items[i] = stripUnknown(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), report)
+		}
+	case t.Kind() == reflect.Map && node.kind == jsonObject:
+		copied.members = slices.Clone(node.members)
+		for i, member := range copied.members {
+			copied.members[i].value = stripUnknown(member.value, t.Elem(), path+"."+member.key, report)
+		}
+	case t.Kind() == reflect.Struct && node.kind == jsonObject:
+		fields := map[string]reflect.Type{"$schema": nil}
+		for i := 0; i < t.NumField(); i++ {
+			if name, _, ok := jsonField(t.Field(i)); ok {
+				fields[name] = t.Field(i).Type
+			}
+		}
+		switch t {
+		case reflect.TypeFor[Config]():
+			fields["include"] = nil
+			fields["profileDefaults"] = reflect.TypeFor[Profile]()
+		case reflect.TypeFor[Profile]():
+			fields["extends"] = nil
+		}
+		copied.members = nil
+		for _, member := range node.members {
+			fieldType, ok := fields[member.key]
+			if !ok {
+				report("removed unknown field %s.%s", path, member.key)
+				continue
+			}
+			if fieldType != nil {
+				member.value = stripUnknown(member.value, fieldType, path+"."+member.key, report)
+			}
+			copied.members = append(copied.members, member)
+		}
+	}
+	return &copied
+}
+
+// setMember replaces the value of key or adds it after $schema.
+func setMember(node *jsonNode, key string, value *jsonNode) *jsonNode {
+	copied := *node
+	copied.members = slices.Clone(node.members)
+	if i := slices.IndexFunc(copied.members, func(m jsonMember) bool { return m.key == key }); i != -1 {
+		copied.members[i].value = value
+		return &copied
+	}
+	i := 0
+	if len(copied.members) > 0 && copied.members[0].key == "$schema" {
+		i = 1
+	}
+	copied.members = slices.Insert(copied.members, i, jsonMember{key: key, value: value})
+	return &copied
+}
+
+// formatJSON prints a document with two space indentation, short objects
+// and arrays of plain values stay on one line. Comments are lost.
+func formatJSON(node *jsonNode) []byte {
+	var b bytes.Buffer
+	writeJSON(&b, node, "")
+	b.WriteByte('\n')
+	return b.Bytes()
+}
+
+const inlineJSONLength = 60
+
+func writeJSON(b *bytes.Buffer, node *jsonNode, indent string) {
+	switch node.kind {
+	case jsonNull:
+		b.WriteString("null")
+	case jsonBool:
+		b.WriteString(strconv.FormatBool(node.bool))
+	case jsonNumber:
+		b.WriteString(node.number)
+	case jsonString:
+		writeJSONString(b, node.string)
+	case jsonArray, jsonObject:
+		opening, closing := "[", "]"
+		count := len(node.items)
+		if node.kind == jsonObject {
+			opening, closing = "{", "}"
+			count = len(node.members)
+		}
+		if count == 0 {
+			b.WriteString(opening + closing)
+			return
+		}
+		if inline := inlineJSON(node); inline != "" {
+			b.WriteString(inline)
+			return
+		}
+		b.WriteString(opening + "\n")
+		for i := 0; i < count; i++ {
+			b.WriteString(indent + "  ")
+			if node.kind == jsonObject {
+				writeJSONString(b, node.members[i].key)
+				b.WriteString(": ")
+				writeJSON(b, node.members[i].value, indent+"  ")
+			} else {
+				writeJSON(b, node.items[i], indent+"  ")
+			}
+			if i < count-1 {
+				b.WriteByte(',')
+			}
+			b.WriteByte('\n')
+		}
+		b.WriteString(indent + closing)
+	}
+}
+
+// inlineJSON returns the one line form of arrays and objects that only hold
+// plain values and are short enough, an empty string otherwise.
+func inlineJSON(node *jsonNode) string {
+	var values []*jsonNode
+	for _, item := range node.items {
+		values = append(values, item)
+	}
+	for _, member := range node.members {
+		values = append(values, member.value)
+	}
+	if slices.ContainsFunc(values, func(value *jsonNode) bool {
+		return value.kind == jsonArray || value.kind == jsonObject
+	}) {
+		return ""
+	}
+
+	var b bytes.Buffer
+	parts := make([]string, 0, len(values))
+	for i, value := range values {
+		b.Reset()
+		if node.kind == jsonObject {
+			writeJSONString(&b, node.members[i].key)
+			b.WriteString(": ")
+		}
+		writeJSON(&b, value, "")
+		parts = append(parts, b.String())
+	}
+	if node.kind == jsonArray {
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+	inline := "{ " + strings.Join(parts, ", ") + " }"
+	if len(inline) > inlineJSONLength {
+		return ""
+	}
+	return inline
+}
+
+func writeJSONString(b *bytes.Buffer, s string) {
+	encoder := json.NewEncoder(b)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	b.Truncate(b.Len() - 1) // Encode ends with a newline
+}
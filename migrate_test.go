@@ -0,0 +1,135 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMigrateConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		changes []string
+	}{
+		{
+			name: "current",
+			in:   `{"$schema": "./config.schema.json", "version": 2, "profiles": []}`,
+			want: `{"$schema": "./config.schema.json", "version": 2, "profiles": []}`,
+		},
+		{
+			name:    "without version",
+			in:      `{"$schema": "./config.schema.json", "profiles": [], "shortcuts": [{"shortcut": {"code": "KeyN", "ctrlKey": true}, "action": "newTerminal"}]}`,
+			changes: []string{"added a binding for newTerminalHere", "added a binding for reopenClosedTerminal", "added a binding for previousPrompt", "added a binding for nextPrompt", "added a binding for copyLastCommandOutput", "set version to 2"},
+		},
+		{
+			name:    "unknown fields",
+			in:      `{"$schema": "./config.schema.json", "oldField": 1, "profiles": [{"name": "a", "command": "sh", "legacy": true}]}`,
+			want:    `{"$schema": "./config.schema.json", "version": 2, "profiles": [{"name": "a", "command": "sh"}]}`,
+			changes: []string{"removed unknown field $.oldField", "removed unknown field $.profiles[0].legacy", "set version to 2"},
+		},
+		{
+			name:    "placeholder profile",
+			in:      `{"$schema": "./config.schema.json", "profiles": [{"name": "create your own profile here"}]}`,
+			changes: []string{`replaced the placeholder profile with "PowerShell"`, "set version to 2"},
+		},
+		{
+			name:    "schema",
+			in:      `{"profiles": []}`,
+			want:    `{"$schema": "./config.schema.json", "version": 2, "profiles": []}`,
+			changes: []string{"added $schema", "set version to 2"},
+		},
+		{
+			name: "bound actions and taken shortcuts are kept",
+			in: `{"$schema": "./config.schema.json", "profiles": [], "shortcuts": [
+				{"shortcut": {"code": "KeyX", "ctrlKey": true}, "action": "newTerminalHere"},
+				{"shortcut": {"code": "KeyT", "shiftKey": true, "ctrlKey": true, "type": "keydown"}, "action": "copy"},
+				{"shortcut": {"code": "ArrowUp", "ctrlKey": true, "shiftKey": true}, "action": "paste"},
+				{"shortcut": {"code": "ArrowDown", "ctrlKey": true, "shiftKey": true}, "action": "paste"},
+				{"shortcut": {"code": "KeyC", "ctrlKey": true, "shiftKey": true}, "action": "paste"}
+			]}`,
+			changes: []string{"set version to 2"},
+		},
+	}
+	for _, test := range tests {
+		migrated, changes, err := migrateConfig(mustParseJSONC(t, test.in))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if test.changes == nil {
+			test.changes = []string{}
+		}
+		if !reflect.DeepEqual(changes, test.changes) {
+			t.Errorf("%s: changes %q, want %q", test.name, changes, test.changes)
+		}
+		if test.want != "" {
+			if got, want := plainJSON(t, migrated), parseTestJSON(t, test.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: got %v, want %v", test.name, got, want)
+			}
+		}
+		if len(changes) > 0 {
+			// the result has to load without errors about the version
+			if _, _, err := migrateConfig(migrated); err != nil {
+				t.Errorf("%s: migrating again: %v", test.name, err)
+			}
+		}
+	}
+}
+
+func TestMigrateDefaultConfig(t *testing.T) {
+	migrated, changes, err := migrateConfig(mustParseJSONC(t, DefaultKeybinds))
+	if err != nil || len(changes) != 0 {
+		t.Fatalf("the default config needs migrating: %q, %v", changes, err)
+	}
+	if _, _, err := parseConfig("config.json", formatJSON(migrated)); err != nil {
+		t.Errorf("the default config doesn't load: %v", err)
+	}
+}
+
+func TestConfigFileVersion(t *testing.T) {
+	tests := []struct {
+		in      string
+		version int
+		err     string
+	}{
+		{`{}`, 1, ""},
+		{`{"version": 1}`, 1, ""},
+		{`{"version": 2}`, 2, ""},
+		{`{"version": "2"}`, 0, "expected a positive integer"},
+		{`{"version": 0}`, 0, "expected a positive integer"},
+		{`{"version": 1.5}`, 0, "expected a positive integer"},
+		{`{"version": 99}`, 0, "version 99 is newer than this term2 understands"},
+	}
+	for _, test := range tests {
+		version, err := configFileVersion(mustParseJSONC(t, test.in))
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("configFileVersion(%s) = %d, %v, want error %q", test.in, version, err, test.err)
+			}
+			continue
+		}
+		if err != nil || version != test.version {
+			t.Errorf("configFileVersion(%s) = %d, %v, want %d", test.in, version, err, test.version)
+		}
+	}
+}
+
+func TestFormatJSON(t *testing.T) {
+	in := `{"$schema": "s", "list": [1, "two", true, null], "nested": {"a": {"b": []}}, "short": {"code": "KeyT", "ctrlKey": true}}`
+	want := `{
+  "$schema": "s",
+  "list": [1, "two", true, null],
+  "nested": {
+    "a": {
+      "b": []
+    }
+  },
+  "short": { "code": "KeyT", "ctrlKey": true }
+}
+`
+	if got := string(formatJSON(mustParseJSONC(t, in))); got != want {
+		t.Errorf("formatJSON = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const defaultCommandThreshold = 10
+
+type CommandNotification struct {
+	Threshold float64 `json:"threshold,omitempty"` // seconds, negative disables
+	Desktop   bool    `json:"desktop,omitempty"`
+}
+
+// Notification is shown as a toast by the frontend, Terminal is the tab it
+// belongs to.
+type Notification struct {
+	Terminal int    `json:"terminal"`
+	Title    string `json:"title"`
+	Body     string `json:"body"`
+	Level    string `json:"level"` // info, success or error
+}
+
+// notifyCommandFinished raises a notification for commands that ran longer
+// than the profile's threshold.
+func (t *Terminal) notifyCommandFinished(record CommandRecord) {
+	settings := CommandNotification{Threshold: defaultCommandThreshold}
+	if t.config.CommandNotification != nil {
+		settings = *t.config.CommandNotification
+	}
+	if settings.Threshold < 0 || record.End == nil {
+		return
+	}
+	duration := record.End.Sub(record.Start)
+	if duration < time.Duration(settings.Threshold*float64(time.Second)) {
+		return
+	}
+
+	notification := Notification{
+		Terminal: t.id,
+		Title:    "Command finished",
+		Level:    "success",
+	}
+	if record.ExitCode != nil && *record.ExitCode != 0 {
+		notification.Title = fmt.Sprintf("Command failed with exit code %d", *record.ExitCode)
+		notification.Level = "error"
+	} else if record.ExitCode == nil {
+		notification.Level = "info"
+	}
+	command := record.Command
+	if command == "" {
+		command = "command"
+	}
+	notification.Body = fmt.Sprintf("%s took %s", command, duration.Round(time.Second))
+	if t.config.Profile != "" {
+		notification.Body += " in " + t.config.Profile
+	}
+
+	t.notify(notification, settings.Desktop)
+}
+
+// notify emits the notification to the frontend and optionally mirrors it on
+// the desktop, clicking the desktop notification focuses the tab.
+func (t *Terminal) notify(notification Notification, desktop bool) {
+	runtime.EventsEmit(t.ctx, "terminal:notification", notification)
+	if !desktop {
+		return
+	}
+	notifier, _ := t.ctx.Value(NotifierKey).(*Notifier)
+	if notifier == nil {
+		return
+	}
+	err := notifier.Notify(notification.Title, notification.Body, func() {
+		runtime.WindowUnminimise(t.ctx)
+		runtime.WindowShow(t.ctx)
+		runtime.EventsEmit(t.ctx, "terminal:focus", t.id)
+	})
+	if err != nil {
+		logger.Println(err)
+	}
+}
+
+// watchForeground follows the foreground process group for shells without
+// integration, it stops as soon as the shell reports OSC 133 marks itself.
+func (t *Terminal) watchForeground(pid int) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	var record *CommandRecord
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		t.mutex.Lock()
+		marked := t.commands.marked
+		cwd := t.cwd
+		t.mutex.Unlock()
+		if marked {
+			return
+		}
+
+		foreground, name, err := foregroundProcess(pid)
+		if err != nil {
+			return
+		}
+		if foreground != pid && record == nil {
+			record = &CommandRecord{Command: name, Cwd: cwd, Start: time.Now()}
+		} else if foreground == pid && record != nil {
+			end := time.Now()
+			record.End = &end
+			t.notifyCommandFinished(*record)
+			record = nil
+		}
+	}
+}
+
+const (
+	policyIgnore    = "ignore"
+	policyToast     = "toast"
+	policyDesktop   = "desktop"
+	policyAttention = "attention"
+)
+
+// NotificationPolicy decides what happens with OSC 9/777 notifications and
+// bells of a profile.
+type NotificationPolicy struct {
+	Osc  string `json:"osc,omitempty"`
+	Bell string `json:"bell,omitempty"`
+}
+
+// rateLimiter is a token bucket, a burst of notifications is let through
+// and after that one every refill interval.
+type rateLimiter struct {
+	tokens float64
+	last   time.Time
+	burst  float64
+	refill time.Duration
+}
+
+func newRateLimiter(burst int, refill time.Duration) *rateLimiter {
+	return &rateLimiter{tokens: float64(burst), burst: float64(burst), refill: refill}
+}
+
+func (r *rateLimiter) Allow() bool {
+	now := time.Now()
+	if !r.last.IsZero() {
+		r.tokens = min(r.burst, r.tokens+float64(now.Sub(r.last))/float64(r.refill))
+	}
+	r.last = now
+	if r.tokens < 1 {
+		return false
+	}
+	r.tokens--
+	return true
+}
+
+func (t *Terminal) notificationPolicy() NotificationPolicy {
+	policy := NotificationPolicy{Osc: policyToast, Bell: policyIgnore}
+	if t.config.Notifications != nil {
+		if t.config.Notifications.Osc != "" {
+			policy.Osc = t.config.Notifications.Osc
+		}
+		if t.config.Notifications.Bell != "" {
+			policy.Bell = t.config.Notifications.Bell
+		}
+	}
+	return policy
+}
+
+// handleNotifyOsc handles `OSC 9 ; body` and `OSC 777 ; notify ; title ; body`.
+func (t *Terminal) handleNotifyOsc(code string, payload []byte) {
+	title := t.config.Profile
+	if title == "" {
+		title = "term2"
+	}
+	var body string
+	switch code {
+	case "9":
+		// ConEmu uses OSC 9 with a numeric first parameter for other commands
+		if sub, _, ok := bytes.Cut(payload, []byte{';'}); ok && isDigits(sub) {
+			return
+		}
+		body = string(payload)
+	case "777":
+		parts := strings.SplitN(string(payload), ";", 3)
+		if parts[0] != "notify" || len(parts) < 2 {
+			return
+		}
+		title = parts[1]
+		if len(parts) == 3 {
+			body = parts[2]
+		}
+	}
+	t.applyNotificationPolicy(t.notificationPolicy().Osc, Notification{
+		Terminal: t.id,
+		Title:    title,
+		Body:     body,
+		Level:    "info",
+	})
+}
+
+func (t *Terminal) handleBell() {
+	title := t.config.Profile
+	if title == "" {
+		title = "term2"
+	}
+	t.applyNotificationPolicy(t.notificationPolicy().Bell, Notification{
+		Terminal: t.id,
+		Title:    title,
+		Body:     "Bell",
+		Level:    "info",
+	})
+}
+
+func (t *Terminal) applyNotificationPolicy(policy string, notification Notification) {
+	if policy == policyIgnore || !t.notifyLimiter.Allow() {
+		return
+	}
+	switch policy {
+	case policyToast:
+		t.notify(notification, false)
+	case policyDesktop:
+		t.notify(notification, true)
+	case policyAttention:
+		if !requestAttention(t.ctx) {
+			t.notify(notification, false)
+		}
+	default:
+		logger.Printf("Unknown notification policy %q\n", policy)
+	}
+}
+
+func isDigits(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
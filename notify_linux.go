@@ -0,0 +1,94 @@
+//go:build linux
+
+package main
+
+import (
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	notificationsName      = "org.freedesktop.Notifications"
+	notificationsPath      = "/org/freedesktop/Notifications"
+	notificationsInterface = "org.freedesktop.Notifications"
+)
+
+// Notifier sends desktop notifications through the freedesktop D-Bus service.
+type Notifier struct {
+	conn    *dbus.Conn
+	actions map[uint32]func()
+	mutex   sync.Mutex
+}
+
+func newNotifier() (*Notifier, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range []string{"ActionInvoked", "NotificationClosed"} {
+		if err := conn.AddMatchSignal(
+			dbus.WithMatchObjectPath(notificationsPath),
+			dbus.WithMatchInterface(notificationsInterface),
+			dbus.WithMatchMember(member),
+		); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	n := &Notifier{
+		conn:    conn,
+		actions: make(map[uint32]func()),
+	}
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+	go n.listen(signals)
+	return n, nil
+}
+
+func (n *Notifier) listen(signals <-chan *dbus.Signal) {
+	for signal := range signals {
+		if len(signal.Body) == 0 {
+			continue
+		}
+		id, ok := signal.Body[0].(uint32)
+		if !ok {
+			continue
+		}
+		n.mutex.Lock()
+		action := n.actions[id]
+		delete(n.actions, id)
+		n.mutex.Unlock()
+		if action != nil && signal.Name == notificationsInterface+".ActionInvoked" {
+			action()
+		}
+	}
+}
+
+// Notify shows a notification, onClick runs when the user activates it.
+func (n *Notifier) Notify(title string, body string, onClick func()) error {
+	actions := []string{}
+	if onClick != nil {
+		actions = []string{"default", "Show"}
+	}
+	var id uint32
+	err := n.conn.Object(notificationsName, notificationsPath).Call(
+		notificationsInterface+".Notify", 0,
+		"term2", uint32(0), "utilities-terminal", title, body, actions,
+		map[string]dbus.Variant{}, int32(-1),
+	).Store(&id)
+	if err != nil {
+		return err
+	}
+	if onClick != nil {
+		n.mutex.Lock()
+		n.actions[id] = onClick
+		n.mutex.Unlock()
+	}
+	return nil
+}
+
+func (n *Notifier) Close() error {
+	return n.conn.Close()
+}
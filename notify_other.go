@@ -0,0 +1,21 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+var errNotifyUnsupported = errors.New("desktop notifications are not supported on this platform")
+
+type Notifier struct{}
+
+func newNotifier() (*Notifier, error) {
+	return nil, errNotifyUnsupported
+}
+
+func (n *Notifier) Notify(_ string, _ string, _ func()) error {
+	return errNotifyUnsupported
+}
+
+func (n *Notifier) Close() error {
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+)
+
+const (
+	maxOscLength = 1 << 20
+	maxCsiLength = 32
+)
+
+const (
+	oscGround uint8 = iota
+	oscEscape
+	oscString
+	oscStringEscape
+	oscCsi
+)
+
+// oscParser picks OSC sequences out of the pty output stream, CSI sequences
+// are passed on with their parameters for the few the backend cares about.
+// Sequences can be split across reads so the state is kept between calls.
+type oscParser struct {
+	state    uint8
+	buf      []byte
+	overflow bool
+	offset   int64
+	start    int64
+	handle   func(code string, payload []byte)
+	csi      func(final byte, params []byte)
+	bell     func()
+}
+
+func newOscParser(handle func(code string, payload []byte), csi func(final byte, params []byte), bell func()) *oscParser {
+	return &oscParser{handle: handle, csi: csi, bell: bell}
+}
+
+func (p *oscParser) Feed(data []byte) {
+	for _, b := range data {
+		p.offset++
+		switch p.state {
+		case oscGround:
+			if b == 0x1b {
+				p.state = oscEscape
+				p.start = p.offset - 1
+			} else if b == 0x07 {
+				p.bell()
+			}
+		case oscEscape:
+			switch b {
+			case ']':
+				p.state = oscString
+				p.buf = p.buf[:0]
+				p.overflow = false
+			case '[':
+				p.state = oscCsi
+				p.buf = p.buf[:0]
+				p.overflow = false
+			case 0x1b:
+				p.start = p.offset - 1
+			default:
+				p.state = oscGround
+			}
+		case oscString:
+			switch b {
+			case 0x07:
+				p.dispatch()
+				p.state = oscGround
+			case 0x1b:
+				p.state = oscStringEscape
+			case 0x18, 0x1a: // CAN and SUB abort the sequence
+				p.state = oscGround
+			default:
+				if len(p.buf) < maxOscLength {
+					p.buf = append(p.buf, b)
+				} else {
+					p.overflow = true
+				}
+			}
+		case oscStringEscape:
+			if b == '\\' {
+				p.dispatch()
+				p.state = oscGround
+			} else if b == ']' {
+				p.state = oscString
+				p.start = p.offset - 2
+				p.buf = p.buf[:0]
+				p.overflow = false
+			} else {
+				p.state = oscGround
+			}
+		case oscCsi:
+			switch {
+			case b >= 0x40 && b <= 0x7e:
+				if !p.overflow {
+					p.csi(b, p.buf)
+				}
+				p.state = oscGround
+			case b >= 0x20 && b <= 0x3f:
+				if len(p.buf) < maxCsiLength {
+					p.buf = append(p.buf, b)
+				} else {
+					p.overflow = true
+				}
+			case b == 0x1b:
+				p.state = oscEscape
+				p.start = p.offset - 1
+			case b == 0x18 || b == 0x1a:
+				p.state = oscGround
+			case b == 0x07:
+				p.bell()
+			}
+		}
+	}
+}
+
+// Offset is the absolute output offset after the last byte fed, while a
+// handler runs it is the offset right after the sequence being handled.
+func (p *oscParser) Offset() int64 {
+	return p.offset
+}
+
+// SequenceStart is the absolute offset of the ESC that opened the sequence
+// being handled.
+func (p *oscParser) SequenceStart() int64 {
+	return p.start
+}
+
+func (p *oscParser) dispatch() {
+	if p.overflow {
+		logger.Printf("Dropped OSC sequence longer than %d bytes\n", maxOscLength)
+		return
+	}
+	code, payload, _ := bytes.Cut(p.buf, []byte{';'})
+	p.handle(string(code), payload)
+}
+
+// parseOsc7 extracts the directory from an OSC 7 `file://host/path` report.
+// Reports from other hosts (ssh sessions) are ignored.
+func parseOsc7(payload []byte) (string, bool) {
+	u, err := url.Parse(string(payload))
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		if hostname, err := os.Hostname(); err != nil || !strings.EqualFold(u.Host, hostname) {
+			return "", false
+		}
+	}
+	path := u.Path
+	if runtime.GOOS == "windows" && len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	if path == "" {
+		return "", false
+	}
+	return path, true
+}
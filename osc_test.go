@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// recordSequences feeds chunks to a parser and lists what it reported.
+func recordSequences(chunks ...string) []string {
+	var events []string
+	var p *oscParser
+	p = newOscParser(
+		func(code string, payload []byte) {
+			events = append(events, fmt.Sprintf("osc %s %q %d-%d", code, payload, p.SequenceStart(), p.Offset()))
+		},
+		func(final byte, params []byte) {
+			events = append(events, fmt.Sprintf("csi %c %q", final, params))
+		},
+		func() {
+			events = append(events, "bell")
+		},
+	)
+	for _, chunk := range chunks {
+		p.Feed([]byte(chunk))
+	}
+	return events
+}
+
+func TestOscParser(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		want   []string
+	}{
+		{
+			name:   "bel terminated",
+			chunks: []string{"ab\x1b]0;title\x07cd"},
+			want:   []string{`osc 0 "title" 2-12`},
+		},
+		{
+			name:   "st terminated",
+			chunks: []string{"\x1b]7;file://host/tmp\x1b\\"},
+			want:   []string{`osc 7 "file://host/tmp" 0-21`},
+		},
+		{
+			name:   "split across reads",
+			chunks: []string{"x\x1b", "]133;", "A\x1b", "\\y"},
+			want:   []string{`osc 133 "A" 1-10`},
+		},
+		{
+			name:   "no payload",
+			chunks: []string{"\x1b]133\x07"},
+			want:   []string{`osc 133 "" 0-6`},
+		},
+		{
+			name:   "cancelled",
+			chunks: []string{"\x1b]0;gone\x18\x1b]2;kept\x07"},
+			want:   []string{`osc 2 "kept" 9-18`},
+		},
+		{
+			name:   "new sequence inside a string",
+			chunks: []string{"\x1b]0;a\x1b]0;b\x07"},
+			want:   []string{`osc 0 "b" 5-11`},
+		},
+		{
+			name:   "csi",
+			chunks: []string{"\x1b[?2004h\x1b[", "1;2", "m"},
+			want:   []string{`csi h "?2004"`, `csi m "1;2"`},
+		},
+		{
+			name:   "long csi dropped",
+			chunks: []string{"\x1b[" + strings.Repeat("1;", maxCsiLength) + "m\x1b[J"},
+			want:   []string{`csi J ""`},
+		},
+		{
+			name:   "bells",
+			chunks: []string{"\x07", "\x1b[\x07m"},
+			want:   []string{"bell", "bell", `csi m ""`},
+		},
+		{
+			name:   "other escapes",
+			chunks: []string{"\x1b(B\x1b\x1b]1;icon\x07"},
+			want:   []string{`osc 1 "icon" 4-13`},
+		},
+	}
+	for _, test := range tests {
+		if got := recordSequences(test.chunks...); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestParseOsc7(t *testing.T) {
+	drive := "/C:/Users/me"
+	if runtime.GOOS == "windows" {
+		drive = "C:/Users/me"
+	}
+	tests := []struct {
+		payload string
+		want    string
+		ok      bool
+	}{
+		{"file:///home/me/src", "/home/me/src", true},
+		{"file://localhost/tmp", "/tmp", true},
+		{"file:///with%20space", "/with space", true},
+		{"file:///C:/Users/me", drive, true},
+		{"file://some-other-host.invalid/tmp", "", false},
+		{"http://localhost/tmp", "", false},
+		{"file://", "", false},
+		{"not a url\x7f", "", false},
+	}
+	for _, test := range tests {
+		got, ok := parseOsc7([]byte(test.payload))
+		if got != test.want || ok != test.ok {
+			t.Errorf("parseOsc7(%q) = %q, %v, want %q, %v", test.payload, got, ok, test.want, test.ok)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Paths are the directories term2 reads and writes. Config holds the config
+// file, assets and certs, State what the app writes on its own like the
+// history and Cache what it can recreate at any time.
+type Paths struct {
+	Config string `json:"config"`
+	State  string `json:"state"`
+	Cache  string `json:"cache"`
+}
+
+func (p Paths) ConfigFile() string {
+	return filepath.Join(p.Config, "config.json")
+}
+
+func (p Paths) Assets() string {
+	return filepath.Join(p.Config, "assets")
+}
+
+func (p Paths) Certs() string {
+	return filepath.Join(p.Config, "certs")
+}
+
+// resolvePaths picks the directories, in order of precedence:
+//   - the --config-dir flag
+//   - the TERM2_HOME environment variable
+//   - .term2-dev in the working directory in dev, it is gitignored
+//   - next to the executable in portable mode, enabled with --portable or a
+//     `portable` file beside the executable
+//   - XDG base directories on Linux, unless the old ~/.term2 exists
+//   - ~/.term2 everywhere else
+func resolvePaths(configDir string, portable bool, dev bool) (Paths, error) {
+	single := func(dir string) (Paths, error) {
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			return Paths{}, err
+		}
+		return Paths{Config: dir, State: dir, Cache: filepath.Join(dir, "cache")}, nil
+	}
+
+	if configDir != "" {
+		return single(configDir)
+	}
+	if home := os.Getenv("TERM2_HOME"); home != "" {
+		return single(home)
+	}
+	if dev {
+		return single(".term2-dev")
+	}
+	if executable, err := os.Executable(); err == nil {
+		dir := filepath.Dir(executable)
+		if _, err := os.Stat(filepath.Join(dir, "portable")); portable || err == nil {
+			return single(dir)
+		}
+	} else if portable {
+		return Paths{}, err
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return Paths{}, errors.New("couldn't find HOMEDIR")
+	}
+	legacy := filepath.Join(homeDir, ".term2")
+	if runtime.GOOS != "linux" {
+		paths := Paths{Config: legacy, State: legacy, Cache: filepath.Join(legacy, "cache")}
+		if cacheDir, err := os.UserCacheDir(); err == nil {
+			paths.Cache = filepath.Join(cacheDir, "term2")
+		}
+		return paths, nil
+	}
+
+	xdg := func(env string, fallback ...string) string {
+		if dir := os.Getenv(env); filepath.IsAbs(dir) {
+			return filepath.Join(dir, "term2")
+		}
+		return filepath.Join(append(append([]string{homeDir}, fallback...), "term2")...)
+	}
+	paths := Paths{
+		Config: xdg("XDG_CONFIG_HOME", ".config"),
+		State:  xdg("XDG_STATE_HOME", ".local", "state"),
+		Cache:  xdg("XDG_CACHE_HOME", ".cache"),
+	}
+	if info, err := os.Stat(legacy); err == nil && info.IsDir() {
+		paths.Config, paths.State = legacy, legacy
+	}
+	return paths, nil
+}
@@ -0,0 +1,49 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// foregroundProcess returns the foreground process group leader on the
+// terminal of pid and its command name.
+func foregroundProcess(pid int) (int, string, error) {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, "", err
+	}
+	// the command name may contain spaces so skip past its closing paren
+	i := strings.LastIndexByte(string(stat), ')')
+	if i == -1 {
+		return 0, "", errors.New("malformed /proc stat")
+	}
+	fields := strings.Fields(string(stat[i+1:]))
+	if len(fields) < 6 {
+		return 0, "", errors.New("malformed /proc stat")
+	}
+	tpgid, err := strconv.Atoi(fields[5])
+	if err != nil || tpgid <= 0 {
+		return 0, "", errors.New("no foreground process")
+	}
+	comm, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", tpgid))
+	if err != nil {
+		return tpgid, "", nil
+	}
+	return tpgid, strings.TrimSpace(string(comm)), nil
+}
+
+// processCwd returns the working directory of the foreground process group
+// on the terminal of pid, falling back to pid itself.
+func processCwd(pid int) (string, error) {
+	if tpgid, _, err := foregroundProcess(pid); err == nil {
+		if cwd, err := os.Readlink(fmt.Sprintf("/proc/%d/cwd", tpgid)); err == nil {
+			return cwd, nil
+		}
+	}
+	return os.Readlink(fmt.Sprintf("/proc/%d/cwd", pid))
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !windows
+
+package main
+
+import "errors"
+
+var errProcUnsupported = errors.New("process inspection is not supported on this platform")
+
+func foregroundProcess(_ int) (int, string, error) {
+	return 0, "", errProcUnsupported
+}
+
+func processCwd(_ int) (string, error) {
+	return "", errProcUnsupported
+}
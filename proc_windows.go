@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var errProcUnsupported = errors.New("process inspection is not supported on this platform")
+
+// foregroundProcess has nothing to go on, a console has no foreground
+// process group.
+func foregroundProcess(_ int) (int, string, error) {
+	return 0, "", errProcUnsupported
+}
+
+// processCwd reads the working directory from the process parameters of pid.
+// PowerShell's Set-Location doesn't change it, there only OSC 7 from the
+// shell integration follows the directory.
+func processCwd(pid int) (string, error) {
+	process, err := windows.OpenProcess(windows.PROCESS_QUERY_INFORMATION|windows.PROCESS_VM_READ, false, uint32(pid))
+	if err != nil {
+		return "", err
+	}
+	defer windows.CloseHandle(process)
+
+	var info windows.PROCESS_BASIC_INFORMATION
+	if err := windows.NtQueryInformationProcess(process, windows.ProcessBasicInformation, unsafe.Pointer(&info), uint32(unsafe.Sizeof(info)), nil); err != nil {
+		return "", err
+	}
+	// only as far as needed, the structures grew with every Windows version
+	var peb windows.PEB
+	if err := readProcess(process, uintptr(unsafe.Pointer(info.PebBaseAddress)), unsafe.Pointer(&peb), unsafe.Offsetof(peb.ProcessParameters)+unsafe.Sizeof(peb.ProcessParameters)); err != nil {
+		return "", err
+	}
+	var params windows.RTL_USER_PROCESS_PARAMETERS
+	if err := readProcess(process, uintptr(unsafe.Pointer(peb.ProcessParameters)), unsafe.Pointer(&params), unsafe.Offsetof(params.CurrentDirectory)+unsafe.Sizeof(params.CurrentDirectory)); err != nil {
+		return "", err
+	}
+	path := params.CurrentDirectory.DosPath
+	if path.Length == 0 {
+		return "", errors.New("empty working directory")
+	}
+	buf := make([]uint16, path.Length/2)
+	if err := readProcess(process, uintptr(unsafe.Pointer(path.Buffer)), unsafe.Pointer(&buf[0]), uintptr(path.Length)); err != nil {
+		return "", err
+	}
+	cwd := windows.UTF16ToString(buf)
+	if len(cwd) > 3 {
+		cwd = strings.TrimSuffix(cwd, `\`) // kept only for a drive root like C:\
+	}
+	return cwd, nil
+}
+
+func readProcess(process windows.Handle, address uintptr, to unsafe.Pointer, size uintptr) error {
+	return windows.ReadProcessMemory(process, address, (*byte)(to), size, nil)
+}
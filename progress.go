@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const appTitle = "term2"
+
+// Progress is the state reported through `OSC 9 ; 4 ; state ; percent`.
+type Progress struct {
+	State   string `json:"state"` // none, normal, error, indeterminate or paused
+	Percent int    `json:"percent"`
+}
+
+var progressStates = []string{"none", "normal", "error", "indeterminate", "paused"}
+
+// parseProgress reads the parameters following `OSC 9 ; 4 ;`.
+func parseProgress(params []byte) (Progress, bool) {
+	fields := bytes.Split(params, []byte{';'})
+	state, err := strconv.Atoi(string(fields[0]))
+	if err != nil || state < 0 || state >= len(progressStates) {
+		return Progress{}, false
+	}
+	progress := Progress{State: progressStates[state]}
+	if len(fields) > 1 {
+		if percent, err := strconv.Atoi(string(fields[1])); err == nil {
+			progress.Percent = min(max(percent, 0), 100)
+		}
+	}
+	if progress.State == "none" {
+		progress.Percent = 0
+	}
+	return progress, true
+}
+
+func (t *Terminal) setProgress(progress Progress) {
+	t.mutex.Lock()
+	changed := t.progress != progress
+	t.progress = progress
+	t.mutex.Unlock()
+	if !changed {
+		return
+	}
+	runtime.EventsEmit(t.ctx, "terminal:progress", t.id, progress)
+	updateWindowTitle(t.ctx)
+}
+
+// aggregateProgress combines the progress of all terminals, errors win over
+// paused tabs and those over normal ones.
+func aggregateProgress(ctx context.Context) Progress {
+	terminals := ctx.Value(TerminalsKey).(*Terminals)
+	terminals.mutex.Lock()
+	defer terminals.mutex.Unlock()
+
+	rank := map[string]int{"none": 0, "indeterminate": 1, "normal": 2, "paused": 3, "error": 4}
+	result := Progress{State: "none"}
+	total, count := 0, 0
+	for _, term := range terminals.terminals {
+		term.mutex.Lock()
+		progress := term.progress
+		term.mutex.Unlock()
+		if progress.State == "" || progress.State == "none" {
+			continue
+		}
+		if rank[progress.State] > rank[result.State] {
+			result.State = progress.State
+		}
+		if progress.State != "indeterminate" {
+			total += progress.Percent
+			count++
+		}
+	}
+	if count > 0 {
+		result.Percent = total / count
+	}
+	return result
+}
+
+func progressSuffix(progress Progress) string {
+	switch progress.State {
+	case "normal":
+		return fmt.Sprintf(" [%d%%]", progress.Percent)
+	case "error":
+		return fmt.Sprintf(" [error %d%%]", progress.Percent)
+	case "paused":
+		return fmt.Sprintf(" [paused %d%%]", progress.Percent)
+	case "indeterminate":
+		return " [...]"
+	}
+	return ""
+}
@@ -0,0 +1,443 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	remoteSocketEnv  = "TERM2_SOCKET"
+	remoteSessionEnv = "TERM2_SESSION_ID"
+	remoteTimeout    = 10 * time.Second
+)
+
+const remoteUsage = `usage: term2 @ <command> [--socket <path>] [--id <id>] [options]
+
+Controls the running term2 from inside one of its terminals. --id picks the
+terminal, it defaults to $TERM2_SESSION_ID and then to the active tab.
+
+commands:
+  list                                   print the open terminals as JSON
+  new-tab [--profile] [--cwd] [--title] [command...]
+                                         open a tab and print its id
+  send-text [--stdin] [text...]          type the text into the terminal
+  set-title [title]                      fix the tab title, no title resets it
+  get-text [--extent all|tail|last-output]
+                                         print the output as plain text
+  close                                  close the terminal
+  focus                                  switch to the terminal
+`
+
+// remoteRequest is what `term2 @` sends over the socket, one per connection.
+type remoteRequest struct {
+	Command string           `json:"command"`
+	Id      *int             `json:"id,omitempty"` // nil is the active terminal
+	Text    string           `json:"text,omitempty"`
+	Extent  string           `json:"extent,omitempty"`
+	Tab     *TerminalRequest `json:"tab,omitempty"`
+}
+
+type remoteResponse struct {
+	Error string          `json:"error,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// RemoteTerminal describes a terminal for `term2 @ list` and the automation API.
+type RemoteTerminal struct {
+	Id       int    `json:"id"`
+	Profile  string `json:"profile"`
+	Title    string `json:"title"`
+	Cwd      string `json:"cwd"`
+	Pid      int    `json:"pid"`
+	Active   bool   `json:"active"`
+	Headless bool   `json:"headless"`
+	Running  bool   `json:"running"`
+	ExitCode *int   `json:"exitCode"`
+}
+
+// pendingTabs hands the id of a tab opened through terminal:open back to
+// whoever asked for it, the frontend passes the ticket on to CreateTerminal.
+type pendingTabs struct {
+	mutex   sync.Mutex
+	next    int
+	waiting map[int]chan error
+	ids     map[int]int
+}
+
+func (p *pendingTabs) add() (int, chan error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.waiting == nil {
+		p.waiting = make(map[int]chan error)
+		p.ids = make(map[int]int)
+	}
+	p.next++
+	done := make(chan error, 1)
+	p.waiting[p.next] = done
+	return p.next, done
+}
+
+func (p *pendingTabs) resolve(ticket int, id int, err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if done, ok := p.waiting[ticket]; ok {
+		p.ids[ticket] = id
+		done <- err
+	}
+}
+
+// remove forgets a ticket and returns the id it was resolved with.
+func (p *pendingTabs) remove(ticket int) int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	id := p.ids[ticket]
+	delete(p.waiting, ticket)
+	delete(p.ids, ticket)
+	return id
+}
+
+// remoteSocketPath picks a socket in a directory only the user can access,
+// the runtime directory on Linux and the temp directory elsewhere. On
+// Windows that is an ACL instead of the mode.
+func remoteSocketPath() (string, error) {
+	name := "term2"
+	if uid := os.Getuid(); uid != -1 {
+		name += "-" + strconv.Itoa(uid)
+	}
+	dir := filepath.Join(os.TempDir(), name)
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); filepath.IsAbs(runtimeDir) {
+		dir = filepath.Join(runtimeDir, "term2")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	// fails when someone else created the directory first
+	if err := restrictToUser(dir, os.ModeDir|0700); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, strconv.Itoa(os.Getpid())+".sock"), nil
+}
+
+// listenRemote starts the remote control server, terminals find it through
+// TERM2_SOCKET.
+func (a *App) listenRemote() {
+	path, err := remoteSocketPath()
+	if err != nil {
+		logger.Println(err)
+		return
+	}
+	os.Remove(path) // left behind by a crashed term2 with the same pid
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		logger.Println(err)
+		return
+	}
+	if err := restrictToUser(path, 0600); err != nil {
+		logger.Println(err)
+	}
+	a.remote = listener
+	a.socket = path
+	logger.Printf("Remote control on %s\n", path)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					logger.Println(err)
+				}
+				return
+			}
+			go a.serveRemote(conn)
+		}
+	}()
+}
+
+func (a *App) serveRemote(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * remoteTimeout))
+
+	var request remoteRequest
+	var response remoteResponse
+	if err := json.NewDecoder(conn).Decode(&request); err != nil {
+		response.Error = "bad request: " + err.Error()
+	} else if data, err := a.handleRemote(request); err != nil {
+		response.Error = err.Error()
+	} else if data != nil {
+		response.Data, _ = json.Marshal(data)
+	}
+	if err := json.NewEncoder(conn).Encode(response); err != nil {
+		logger.Println(err)
+	}
+}
+
+var remoteCommands = []string{"list", "new-tab", "send-text", "set-title", "get-text", "close", "focus"}
+
+func (a *App) handleRemote(request remoteRequest) (any, error) {
+	if !slices.Contains(remoteCommands, request.Command) {
+		return nil, fmt.Errorf("unknown command %q", request.Command)
+	}
+	switch request.Command {
+	case "list":
+		return a.remoteList(), nil
+	case "new-tab":
+		return a.remoteNewTab(request.Tab)
+	}
+
+	term, err := a.remoteTarget(request.Id)
+	if err != nil {
+		return nil, err
+	}
+	switch request.Command {
+	case "send-text":
+		return nil, term.send([]byte(request.Text))
+	case "set-title":
+		term.mutex.Lock()
+		term.config.Title = request.Text
+		term.mutex.Unlock()
+		term.updateTitle()
+	case "get-text":
+		return term.text(request.Extent)
+	case "close":
+		a.closeTerminal(term)
+	case "focus":
+		runtime.WindowUnminimise(a.ctx)
+		runtime.WindowShow(a.ctx)
+		runtime.EventsEmit(a.ctx, "terminal:focus", term.id)
+	}
+	return nil, nil
+}
+
+// remoteTarget picks the terminal a request is about, nil means the active one.
+func (a *App) remoteTarget(id *int) (*Terminal, error) {
+	if id != nil {
+		return a.terminal(*id)
+	}
+	terminals := a.ctx.Value(TerminalsKey).(*Terminals)
+	terminals.mutex.Lock()
+	active := terminals.active
+	terminals.mutex.Unlock()
+	if active == -1 {
+		return nil, errors.New("no active terminal, pass --id")
+	}
+	return a.terminal(active)
+}
+
+func (a *App) remoteList() []RemoteTerminal {
+	terminals := a.ctx.Value(TerminalsKey).(*Terminals)
+	terminals.mutex.Lock()
+	list := make([]*Terminal, 0, len(terminals.terminals))
+	for _, term := range terminals.terminals {
+		list = append(list, term)
+	}
+	terminals.mutex.Unlock()
+	slices.SortFunc(list, func(a, b *Terminal) int { return a.id - b.id })
+
+	result := make([]RemoteTerminal, 0, len(list))
+	for _, term := range list {
+		result = append(result, a.describeTerminal(term))
+	}
+	return result
+}
+
+func (a *App) describeTerminal(term *Terminal) RemoteTerminal {
+	terminals := a.ctx.Value(TerminalsKey).(*Terminals)
+	terminals.mutex.Lock()
+	active := terminals.active
+	terminals.mutex.Unlock()
+
+	info := RemoteTerminal{
+		Id:       term.id,
+		Profile:  term.config.Profile,
+		Title:    term.displayTitle(),
+		Cwd:      a.terminalCwd(term.id),
+		Active:   term.id == active,
+		Headless: term.headless,
+		Running:  term.ctx.Err() == nil,
+	}
+	if term.cmd.Process != nil {
+		info.Pid = term.cmd.Process.Pid
+	}
+	term.mutex.Lock()
+	info.ExitCode = term.exitCode
+	term.mutex.Unlock()
+	return info
+}
+
+// remoteNewTab asks the frontend for a tab and waits until it is created.
+func (a *App) remoteNewTab(request *TerminalRequest) (int, error) {
+	if request == nil {
+		request = &TerminalRequest{}
+	}
+	ticket, done := a.tabs.add()
+	defer a.tabs.remove(ticket)
+	request.Ticket = ticket
+	runtime.EventsEmit(a.ctx, "terminal:open", request)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return -1, err
+		}
+		return a.tabs.remove(ticket), nil
+	case <-time.After(remoteTimeout):
+		return -1, errors.New("timed out waiting for the tab to open")
+	}
+}
+
+// text returns the output of a terminal as plain text. all is everything in
+// the scrollback, tail as many of its last lines as the terminal has rows and
+// last-output the output of the last finished command. tail only approximates
+// the screen, there is no screen model to follow cursor movement, the
+// alternate screen or wrapping.
+func (t *Terminal) text(extent string) (string, error) {
+	switch extent {
+	case "", "all", "tail":
+	case "last-output":
+		return t.lastOutput()
+	default:
+		return "", fmt.Errorf("unknown extent %q, use all, tail or last-output", extent)
+	}
+
+	t.mutex.Lock()
+	output, _ := t.scrollback.Slice(0, t.scrollback.End())
+	t.mutex.Unlock()
+	text := strings.TrimRight(stripAnsi(output), "\n")
+	if extent != "tail" {
+		return text, nil
+	}
+	size, err := t.pty.GetSize()
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Split(text, "\n")
+	return strings.Join(lines[max(len(lines)-int(size.Rows), 0):], "\n"), nil
+}
+
+func (t *Terminal) lastOutput() (string, error) {
+	t.mutex.Lock()
+	records := t.commands.records
+	if len(records) == 0 {
+		t.mutex.Unlock()
+		return "", fmt.Errorf("no finished commands in terminal %d", t.id)
+	}
+	record := records[len(records)-1]
+	output, _ := t.scrollback.Slice(record.OutputStart, record.OutputEnd)
+	t.mutex.Unlock()
+	return strings.Trim(stripAnsi(output), "\n"), nil
+}
+
+// runRemoteCommand runs `term2 @ <command>` and returns the exit code.
+func runRemoteCommand(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprint(os.Stderr, remoteUsage)
+		return 2
+	}
+	command := args[0]
+	if !slices.Contains(remoteCommands, command) {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, remoteUsage)
+		return 2
+	}
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, remoteUsage) }
+	socket := flags.String("socket", os.Getenv(remoteSocketEnv), "socket of the running term2")
+	id := flags.Int("id", -1, "terminal to act on")
+	launch := &launchArgs{}
+	flags.StringVar(&launch.profile, "profile", "", "profile of the new tab")
+	flags.StringVar(&launch.cwd, "cwd", "", "working directory of the new tab")
+	flags.StringVar(&launch.title, "title", "", "fixed title of the new tab")
+	stdin := flags.Bool("stdin", false, "send what is read from stdin")
+	extent := flags.String("extent", "all", "all, tail or last-output")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if *socket == "" {
+		fmt.Fprintln(os.Stderr, remoteSocketEnv+" is not set, run this inside term2 or pass --socket")
+		return 1
+	}
+
+	request := remoteRequest{Command: command, Extent: *extent}
+	if *id != -1 {
+		request.Id = id
+	} else if session, err := strconv.Atoi(os.Getenv(remoteSessionEnv)); err == nil {
+		request.Id = &session
+	}
+	switch command {
+	case "new-tab":
+		launch.command = flags.Args()
+		cwd, err := os.Getwd()
+		if err != nil {
+			cwd = ""
+		}
+		request.Tab = launch.request(cwd)
+	case "send-text":
+		if *stdin {
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			request.Text = string(data)
+		} else {
+			request.Text = strings.Join(flags.Args(), " ")
+		}
+	case "set-title":
+		request.Text = strings.Join(flags.Args(), " ")
+	}
+
+	response, err := sendRemote(*socket, request)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if response.Error != "" {
+		fmt.Fprintln(os.Stderr, response.Error)
+		return 1
+	}
+	switch command {
+	case "list":
+		var list []RemoteTerminal
+		json.Unmarshal(response.Data, &list)
+		output, _ := json.MarshalIndent(list, "", "  ")
+		fmt.Println(string(output))
+	case "new-tab":
+		var id int
+		json.Unmarshal(response.Data, &id)
+		fmt.Println(id)
+	case "get-text":
+		var text string
+		json.Unmarshal(response.Data, &text)
+		fmt.Println(text)
+	}
+	return 0
+}
+
+func sendRemote(socket string, request remoteRequest) (*remoteResponse, error) {
+	conn, err := net.DialTimeout("unix", socket, remoteTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * remoteTimeout))
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		return nil, err
+	}
+	var response remoteResponse
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
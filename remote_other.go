@@ -0,0 +1,10 @@
+//go:build !windows
+
+package main
+
+import "os"
+
+// restrictToUser makes path accessible to the user only.
+func restrictToUser(path string, perm os.FileMode) error {
+	return os.Chmod(path, perm.Perm())
+}
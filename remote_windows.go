@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// restrictToUser replaces the ACL of path with one that only grants the
+// user access, the mode bits mean nothing on Windows. Directories pass
+// theirs on to what is created inside.
+func restrictToUser(path string, perm os.FileMode) error {
+	user, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		return err
+	}
+	inherit := ""
+	if perm.IsDir() {
+		inherit = "OICI"
+	}
+	sd, err := windows.SecurityDescriptorFromString("D:P(A;" + inherit + ";GA;;;" + user.User.Sid.String() + ")")
+	if err != nil {
+		return err
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return err
+	}
+	return windows.SetNamedSecurityInfo(path, windows.SE_FILE_OBJECT,
+		windows.DACL_SECURITY_INFORMATION|windows.PROTECTED_DACL_SECURITY_INFORMATION, nil, nil, dacl, nil)
+}
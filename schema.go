@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+const schemaFile = "config.schema.json"
+
+// schemaEnums lists the allowed values of string fields, by type and field.
+var schemaEnums = map[string][]string{
+	"Shortcut.code":           shortcutCodes,
+	"Shortcut.type":           {"keydown", "keyup"},
+	"ShortcutBinding.action":  configActions,
+	"Profile.clipboard":       clipboardPolicies,
+	"NotificationPolicy.osc":  notificationPolicies,
+	"NotificationPolicy.bell": notificationPolicies,
+	"SessionSettings.restore": restorePolicies,
+}
+
+// schemaRequired are the types whose required fields are marked in the
+// schema. Everything else can be split across includes, profileDefaults
+// and extends, so only the app can tell whether it is complete.
+var schemaRequired = map[string]bool{
+	"Font":            true,
+	"Shortcut":        true,
+	"ShortcutBinding": true,
+}
+
+// configSchema builds a JSON Schema for config.json from the Go types.
+func configSchema() map[string]any {
+	definitions := make(map[string]any)
+	root := schemaFor(reflect.TypeFor[Config](), definitions)
+
+	properties := root["properties"].(map[string]any)
+	properties["$schema"] = map[string]any{"type": "string"}
+	properties["include"] = map[string]any{
+		"description": "files merged in before this one, relative to this file",
+		"oneOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+	}
+
+	profile := definitions["Profile"].(map[string]any)
+	profile["required"] = []string{"name"}
+	profile["properties"].(map[string]any)["extends"] = map[string]any{
+		"description": "name of the profile to inherit from",
+		"type":        "string",
+	}
+
+	defaults := make(map[string]any)
+	defaultProperties := make(map[string]any)
+	for key, value := range profile["properties"].(map[string]any) {
+		if key != "name" && key != "shortcut" && key != "extends" {
+			defaultProperties[key] = value
+		}
+	}
+	defaults["type"] = "object"
+	defaults["description"] = "fields every profile starts with"
+	defaults["properties"] = defaultProperties
+	defaults["additionalProperties"] = false
+	properties["profileDefaults"] = defaults
+
+	root["$schema"] = "http://json-schema.org/draft-07/schema#"
+	root["title"] = "term2 config"
+	root["definitions"] = definitions
+	return root
+}
+
+func schemaFor(t reflect.Type, definitions map[string]any) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaFor(t.Elem(), definitions)
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Int:
+		return map[string]any{"type": "integer"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem(), definitions)}
+	case reflect.Map:
+		values := schemaFor(t.Elem(), definitions)
+		if t.Elem().Kind() == reflect.Pointer {
+			values = map[string]any{"oneOf": []any{values, map[string]any{"type": "null"}}}
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := definitions[name]; !ok {
+			definitions[name] = nil // breaks cycles
+			properties := make(map[string]any)
+			required := []string{}
+			for i := 0; i < t.NumField(); i++ {
+				field, optional, ok := jsonField(t.Field(i))
+				if !ok {
+					continue
+				}
+				property := schemaFor(t.Field(i).Type, definitions)
+				if enum, ok := schemaEnums[name+"."+field]; ok {
+					property["enum"] = enum
+				}
+				properties[field] = property
+				if !optional {
+					required = append(required, field)
+				}
+			}
+			definition := map[string]any{
+				"type":                 "object",
+				"properties":           properties,
+				"additionalProperties": false,
+			}
+			if schemaRequired[name] && len(required) > 0 {
+				definition["required"] = required
+			}
+			if name == "Config" {
+				delete(definitions, name)
+				return definition
+			}
+			definitions[name] = definition
+		}
+		return map[string]any{"$ref": "#/definitions/" + name}
+	}
+	panic("configSchema: unsupported type " + t.String())
+}
+
+func marshalSchema() []byte {
+	data, err := json.MarshalIndent(configSchema(), "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return append(data, '\n')
+}
+
+// writeSchema keeps the schema next to the config up to date so editors
+// can pick it up through $schema.
+func writeSchema(dir string) error {
+	target := filepath.Join(dir, schemaFile)
+	data := marshalSchema()
+	if existing, err := os.ReadFile(target); err == nil && bytes.Equal(existing, data) {
+		return nil
+	}
+	return os.WriteFile(target, data, 0644)
+}
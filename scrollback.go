@@ -0,0 +1,40 @@
+package main
+
+// scrollback keeps the tail of a terminal's output, addressed by absolute
+// byte offsets since the terminal was created.
+type scrollback struct {
+	buf   []byte
+	start int64
+	limit int
+}
+
+const scrollbackLimit = 2 << 20
+
+func newScrollback(limit int) *scrollback {
+	return &scrollback{limit: limit}
+}
+
+func (s *scrollback) Write(p []byte) {
+	s.buf = append(s.buf, p...)
+	if over := len(s.buf) - s.limit; over > s.limit/4 {
+		s.buf = append(make([]byte, 0, s.limit+s.limit/4), s.buf[over:]...)
+		s.start += int64(over)
+	}
+}
+
+// End is the absolute offset after the last byte written.
+func (s *scrollback) End() int64 {
+	return s.start + int64(len(s.buf))
+}
+
+// Slice copies the retained part of [from, to). The second return value is
+// false when the beginning of the range has already been dropped.
+func (s *scrollback) Slice(from, to int64) ([]byte, bool) {
+	complete := from >= s.start
+	from = max(from, s.start)
+	to = min(to, s.End())
+	if from >= to {
+		return nil, complete
+	}
+	return append([]byte(nil), s.buf[from-s.start:to-s.start]...), complete
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	restoreAsk    = "ask"
+	restoreAlways = "always"
+	restoreNever  = "never"
+
+	sessionDebounce = time.Second
+	snapshotPeriod  = 30 * time.Second
+)
+
+var restorePolicies = []string{restoreAsk, restoreAlways, restoreNever}
+
+// SessionSettings controls how the tabs are brought back after a restart or
+// a crash.
+type SessionSettings struct {
+	Restore    string `json:"restore,omitempty"`    // ask (default), always or never
+	Scrollback bool   `json:"scrollback,omitempty"` // keep the output of every tab, gzipped in the state directory
+}
+
+func (s *SessionSettings) setDefaults() {
+	s.Restore = restoreAsk
+}
+
+// Session is what session.json holds, the tabs in order.
+type Session struct {
+	Terminals []SessionTerminal `json:"terminals"`
+}
+
+type SessionTerminal struct {
+	WorkspaceTerminal
+	ShownTitle string `json:"shownTitle"`
+	Scrollback string `json:"scrollback,omitempty"` // file in the session directory
+}
+
+// sessionState follows the tabs and writes them to the state directory
+// whenever they change.
+type sessionState struct {
+	mutex     sync.Mutex
+	order     []int
+	ready     bool // the last session was restored or discarded, it can be overwritten
+	changed   chan struct{}
+	snapshots map[int]int64 // scrollback end at the last snapshot
+}
+
+func newSessionState() *sessionState {
+	return &sessionState{
+		changed:   make(chan struct{}, 1),
+		snapshots: make(map[int]int64),
+	}
+}
+
+// touch schedules a save, it is cheap enough to call on every change.
+func (s *sessionState) touch() {
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+func touchSession(ctx context.Context) {
+	if session, _ := ctx.Value(SessionKey).(*sessionState); session != nil {
+		session.touch()
+	}
+}
+
+func (a *App) sessionFile() string {
+	return filepath.Join(a.paths.State, "session.json")
+}
+
+func (a *App) sessionDir() string {
+	return filepath.Join(a.paths.State, "session")
+}
+
+func (a *App) sessionSettings() SessionSettings {
+	a.configMutex.Lock()
+	defer a.configMutex.Unlock()
+	if a.config == nil || a.config.Session == nil {
+		settings := SessionSettings{}
+		settings.setDefaults()
+		return settings
+	}
+	return *a.config.Session
+}
+
+// SetTabOrder tells the backend the order of the tabs so it can be restored.
+func (a *App) SetTabOrder(ids []int) {
+	session := a.ctx.Value(SessionKey).(*sessionState)
+	session.mutex.Lock()
+	session.order = ids
+	session.mutex.Unlock()
+	session.touch()
+}
+
+// watchSession saves the session shortly after changes and snapshots the
+// scrollback every now and then.
+func (a *App) watchSession() {
+	session := a.ctx.Value(SessionKey).(*sessionState)
+	ticker := time.NewTicker(snapshotPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-session.changed:
+			time.Sleep(sessionDebounce)
+			select {
+			case <-session.changed:
+			default:
+			}
+			a.saveSession(false)
+		case <-ticker.C:
+			a.saveSession(true)
+		}
+	}
+}
+
+// saveSession writes session.json and, with snapshots, the scrollback of
+// every tab that has new output.
+func (a *App) saveSession(snapshots bool) {
+	session := a.ctx.Value(SessionKey).(*sessionState)
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	if !session.ready {
+		return
+	}
+	settings := a.sessionSettings()
+	if settings.Restore == restoreNever {
+		a.removeSession()
+		return
+	}
+
+	saved := Session{Terminals: make([]SessionTerminal, 0, len(session.order))}
+	keep := make(map[string]bool)
+	for _, id := range session.order {
+		term, err := a.terminal(id)
+		if err != nil || term.headless {
+			continue
+		}
+		entry := SessionTerminal{
+			WorkspaceTerminal: a.workspaceTerminal(term),
+			ShownTitle:        term.displayTitle(),
+		}
+		// the profile's shell starts fresh, nothing is run again
+		entry.Input = ""
+		entry.Command = nil
+		if settings.Scrollback {
+			entry.Scrollback = fmt.Sprintf("%d.gz", id)
+			keep[entry.Scrollback] = true
+			if snapshots {
+				if err := a.snapshot(session, term, entry.Scrollback); err != nil {
+					logger.Println(err)
+				}
+			}
+		}
+		saved.Terminals = append(saved.Terminals, entry)
+	}
+
+	if snapshots || !settings.Scrollback {
+		entries, _ := os.ReadDir(a.sessionDir())
+		for _, entry := range entries {
+			if !keep[entry.Name()] {
+				os.Remove(filepath.Join(a.sessionDir(), entry.Name()))
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		logger.Println(err)
+		return
+	}
+	if err := os.MkdirAll(a.paths.State, 0755); err != nil {
+		logger.Println(err)
+		return
+	}
+	if err := writeFileAtomic(a.sessionFile(), append(data, '\n'), 0600); err != nil {
+		logger.Println(err)
+	}
+}
+
+func (a *App) snapshot(session *sessionState, term *Terminal, name string) error {
+	term.mutex.Lock()
+	end := term.scrollback.End()
+	if session.snapshots[term.id] == end {
+		term.mutex.Unlock()
+		return nil
+	}
+	output, _ := term.scrollback.Slice(0, end)
+	term.mutex.Unlock()
+
+	if err := os.MkdirAll(a.sessionDir(), 0700); err != nil {
+		return err
+	}
+	if err := writeSnapshot(filepath.Join(a.sessionDir(), name), output); err != nil {
+		return err
+	}
+	session.snapshots[term.id] = end
+	return nil
+}
+
+func (a *App) removeSession() {
+	if err := os.Remove(a.sessionFile()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		logger.Println(err)
+	}
+	if err := os.RemoveAll(a.sessionDir()); err != nil {
+		logger.Println(err)
+	}
+}
+
+// RestoreSession offers to reopen the tabs of the last session, it only does
+// something the first time it is called. The tabs come back in order with
+// the output they had.
+func (a *App) RestoreSession() ([]TerminalRequest, error) {
+	session := a.ctx.Value(SessionKey).(*sessionState)
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	if session.ready {
+		return nil, nil
+	}
+	defer func() {
+		session.ready = true
+	}()
+
+	data, err := os.ReadFile(a.sessionFile())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var saved Session
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("%s: %w", a.sessionFile(), err)
+	}
+	if len(saved.Terminals) == 0 {
+		return nil, nil
+	}
+
+	switch a.sessionSettings().Restore {
+	case restoreNever:
+		return nil, nil
+	case restoreAsk:
+		titles := make([]string, len(saved.Terminals))
+		for i, term := range saved.Terminals {
+			titles[i] = term.ShownTitle
+		}
+		answer, err := runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:          runtime.QuestionDialog,
+			Title:         "Restore session",
+			Message:       fmt.Sprintf("Reopen the %d tabs of the last session?\n\n%s", len(titles), strings.Join(titles, "\n")),
+			Buttons:       []string{"Restore", "Discard"},
+			DefaultButton: "Restore",
+			CancelButton:  "Discard",
+		})
+		if err != nil {
+			return nil, err
+		}
+		if answer != "Restore" && answer != "Yes" {
+			return nil, nil
+		}
+	}
+
+	requests := Workspace{Terminals: make([]WorkspaceTerminal, len(saved.Terminals))}
+	for i, term := range saved.Terminals {
+		requests.Terminals[i] = term.WorkspaceTerminal
+	}
+	result := requests.requests(a.sessionFile())
+	for i, term := range saved.Terminals {
+		if term.Scrollback == "" {
+			continue
+		}
+		output, err := readSnapshot(filepath.Join(a.sessionDir(), filepath.Base(term.Scrollback)))
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				logger.Println(err)
+			}
+			continue
+		}
+		result[i].Scrollback = output
+	}
+	logger.Printf("Restoring %d tabs\n", len(result))
+	return result, nil
+}
+
+func readSnapshot(file string) ([]byte, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	reader, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(io.LimitReader(reader, scrollbackLimit+scrollbackLimit/4))
+}
+
+// writeSnapshot gzips output into file.
+func writeSnapshot(file string, output []byte) error {
+	var b bytes.Buffer
+	writer := gzip.NewWriter(&b)
+	writer.Write(output)
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return writeFileAtomic(file, b.Bytes(), 0600)
+}
+
+// writeFileAtomic replaces file so that readers and crashes see either the
+// old or the new content.
+func writeFileAtomic(file string, data []byte, perm os.FileMode) error {
+	temp := file + ".tmp"
+	os.Remove(temp) // a leftover would keep its mode
+	if err := os.WriteFile(temp, data, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(temp, file); err != nil {
+		os.Remove(temp)
+		return err
+	}
+	return nil
+}
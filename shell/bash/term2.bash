@@ -0,0 +1,74 @@
+# term2 shell integration for bash, loaded through --rcfile.
+# It sources the files bash would have read on its own before adding the hooks.
+
+if [ -n "$TERM2_BASH_LOGIN" ]; then
+	unset TERM2_BASH_LOGIN
+	[ -r /etc/profile ] && . /etc/profile
+	for __term2_file in ~/.bash_profile ~/.bash_login ~/.profile; do
+		if [ -r "$__term2_file" ]; then
+			. "$__term2_file"
+			break
+		fi
+	done
+	unset __term2_file
+else
+	[ -r ~/.bashrc ] && . ~/.bashrc
+fi
+
+if [ -n "$__term2_loaded" ] || [ -z "$TERM2_SHELL_INTEGRATION" ]; then
+	return 0
+fi
+__term2_loaded=1
+
+__term2_urlencode() {
+	local LC_ALL=C str="$1" out="" c i
+	for ((i = 0; i < ${#str}; i++)); do
+		c="${str:i:1}"
+		case "$c" in
+		[a-zA-Z0-9/._~-]) out+="$c" ;;
+		*) printf -v c '%%%02X' "'$c"; out+="$c" ;;
+		esac
+	done
+	printf '%s' "$out"
+}
+
+__term2_preexec() {
+	local cmd
+	cmd="$(HISTTIMEFORMAT= builtin history 1)"
+	cmd="${cmd#"${cmd%%[![:space:]]*}"}"
+	cmd="${cmd#*[[:space:]]}"
+	cmd="${cmd#"${cmd%%[![:space:]]*}"}"
+	printf '\e]133;C;cmdline_url=%s\a' "$(__term2_urlencode "$cmd")"
+	printf '\e]2;%s\a' "$cmd"
+}
+
+__term2_precmd() {
+	__term2_status=$?
+	if [ -n "$__term2_started" ]; then
+		printf '\e]133;D;%s\a' "$__term2_status"
+	fi
+	__term2_started=1
+	printf '\e]7;file://%s%s\a' "$HOSTNAME" "$(__term2_urlencode "$PWD")"
+	printf '\e]2;%s\a' "${PWD/#$HOME/\~}"
+	return $__term2_status
+}
+
+# runs after the user's prompt commands so prompts rebuilt on every
+# render (starship, powerline, ...) still get the marks
+__term2_prompt() {
+	case "$PS1" in
+	*'133;A'*) ;;
+	*) PS1='\[\e]133;A\a\]'"$PS1"'\[\e]133;B\a\]' ;;
+	esac
+	case "$PS0" in
+	*__term2_preexec*) ;;
+	*) PS0="$PS0"'$(__term2_preexec)' ;;
+	esac
+	return $__term2_status
+}
+
+if [[ "$(declare -p PROMPT_COMMAND 2>/dev/null)" == "declare -a"* ]]; then
+	PROMPT_COMMAND=(__term2_precmd "${PROMPT_COMMAND[@]}" __term2_prompt)
+else
+	PROMPT_COMMAND="__term2_precmd${PROMPT_COMMAND:+; $PROMPT_COMMAND}; __term2_prompt"
+fi
@@ -0,0 +1,25 @@
+# term2 shell integration for fish, loaded through --init-command
+
+if not status is-interactive; or set -q __term2_loaded; or not set -q TERM2_SHELL_INTEGRATION
+    exit 0
+end
+set -g __term2_loaded 1
+
+function __term2_preexec --on-event fish_preexec
+    printf '\e]133;C;cmdline_url=%s\a' (string escape --style=url -- $argv[1])
+end
+
+function __term2_postexec --on-event fish_postexec
+    printf '\e]133;D;%s\a' $status
+end
+
+function __term2_cwd --on-event fish_prompt
+    printf '\e]7;file://%s%s\a' $hostname (string escape --style=url -- $PWD)
+end
+
+functions -c fish_prompt __term2_original_prompt
+function fish_prompt
+    printf '\e]133;A\a'
+    __term2_original_prompt
+    printf '\e]133;B\a'
+end
@@ -0,0 +1,43 @@
+# term2 shell integration for PowerShell and Windows PowerShell,
+# loaded through -NoExit -Command after the user's profile.
+
+if ($Global:__Term2Loaded -or -not $env:TERM2_SHELL_INTEGRATION) {
+    return
+}
+$Global:__Term2Loaded = $true
+$Global:__Term2Running = $false
+$Global:__Term2OriginalPrompt = $function:prompt
+
+function Global:__Term2-Osc([string]$Sequence) {
+    [Console]::Write("$([char]0x1b)]$Sequence$([char]0x07)")
+}
+
+function Global:prompt {
+    $success = $?
+    $exitCode = $Global:LASTEXITCODE
+    if ($Global:__Term2Running) {
+        $Global:__Term2Running = $false
+        $status = if ($success) { 0 } elseif ($exitCode) { $exitCode } else { 1 }
+        __Term2-Osc "133;D;$status"
+    }
+    if ($PWD.Provider.Name -eq "FileSystem") {
+        __Term2-Osc "7;$(([System.Uri]$PWD.ProviderPath).AbsoluteUri)"
+    }
+    __Term2-Osc "2;$($PWD.Path)"
+    __Term2-Osc "133;A"
+    $prompt = & $Global:__Term2OriginalPrompt
+    $Global:LASTEXITCODE = $exitCode
+    "$prompt$([char]0x1b)]133;B$([char]0x07)"
+}
+
+if (Get-Module PSReadLine) {
+    function Global:PSConsoleHostReadLine {
+        $line = [Microsoft.PowerShell.PSConsoleReadLine]::ReadLine($Host.Runspace, $ExecutionContext)
+        if ($line.Trim()) {
+            $Global:__Term2Running = $true
+            __Term2-Osc "133;C;cmdline_url=$([System.Uri]::EscapeDataString($line))"
+            __Term2-Osc "2;$line"
+        }
+        $line
+    }
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	maxTitleStack        = 10
+	maxTitleLength       = 256
+	defaultTitleTemplate = "{title}"
+)
+
+// titleEntry is what `CSI 22 t` saves and `CSI 23 t` restores.
+type titleEntry struct {
+	title    string
+	iconName string
+}
+
+// handleTitleOsc handles OSC 0 (icon name and title), 1 (icon name) and
+// 2 (title).
+func (t *Terminal) handleTitleOsc(code string, payload []byte) {
+	title := string(payload)
+	if len(title) > maxTitleLength {
+		title = strings.ToValidUTF8(title[:maxTitleLength], "")
+	}
+	t.mutex.Lock()
+	if code == "0" || code == "1" {
+		t.title.iconName = title
+	}
+	if code == "0" || code == "2" {
+		t.title.title = title
+	}
+	t.mutex.Unlock()
+	t.updateTitle()
+}
+
+// handleCsi only cares about the title stack window operations,
+// `CSI 22 ; Ps t` pushes and `CSI 23 ; Ps t` pops. Ps 1 is the icon name,
+// 2 the title and 0 or nothing both.
+func (t *Terminal) handleCsi(final byte, params []byte) {
+	if final != 't' {
+		return
+	}
+	fields := bytes.Split(params, []byte{';'})
+	op, err := strconv.Atoi(string(fields[0]))
+	if err != nil || (op != 22 && op != 23) {
+		return
+	}
+	which := 0
+	if len(fields) > 1 && len(fields[1]) > 0 {
+		if which, err = strconv.Atoi(string(fields[1])); err != nil || which > 2 {
+			return
+		}
+	}
+
+	t.mutex.Lock()
+	if op == 22 {
+		entry := t.title
+		if len(t.titleStack) > 0 {
+			// keep the part that is not pushed as it was
+			top := t.titleStack[len(t.titleStack)-1]
+			if which == 1 {
+				entry.title = top.title
+			} else if which == 2 {
+				entry.iconName = top.iconName
+			}
+		}
+		if len(t.titleStack) == maxTitleStack {
+			t.titleStack = append(t.titleStack[:0], t.titleStack[1:]...)
+		}
+		t.titleStack = append(t.titleStack, entry)
+		t.mutex.Unlock()
+		return
+	}
+	if len(t.titleStack) == 0 {
+		t.mutex.Unlock()
+		return
+	}
+	entry := t.titleStack[len(t.titleStack)-1]
+	t.titleStack = t.titleStack[:len(t.titleStack)-1]
+	if which != 2 {
+		t.title.iconName = entry.iconName
+	}
+	if which != 1 {
+		t.title.title = entry.title
+	}
+	t.mutex.Unlock()
+	t.updateTitle()
+}
+
+// displayTitle fills the profile's title template, programs that never set
+// a title show the profile name.
+func (t *Terminal) displayTitle() string {
+	t.mutex.Lock()
+	title, cwd := t.title.title, t.cwd
+	if title == "" {
+		title = t.title.iconName
+	}
+	if t.config.Title != "" { // set-title can change it
+		title = t.config.Title
+	}
+	t.mutex.Unlock()
+	if title == "" {
+		title = t.config.Profile
+	}
+	if title == "" {
+		title = appTitle
+	}
+
+	template := t.config.TitleTemplate
+	if template == "" {
+		template = defaultTitleTemplate
+	}
+	return strings.NewReplacer(
+		"{title}", title,
+		"{profile}", t.config.Profile,
+		"{cwd}", cwd,
+		"{id}", strconv.Itoa(t.id),
+	).Replace(template)
+}
+
+func (t *Terminal) updateTitle() {
+	title := t.displayTitle()
+	t.mutex.Lock()
+	changed := t.shownTitle != title
+	t.shownTitle = title
+	t.mutex.Unlock()
+	if !changed {
+		return
+	}
+	runtime.EventsEmit(t.ctx, "terminal:title", t.id, title)
+	updateWindowTitle(t.ctx)
+	touchSession(t.ctx)
+}
+
+func (a *App) GetTitle(id int) (string, error) {
+	term, err := a.terminal(id)
+	if err != nil {
+		return "", err
+	}
+	return term.displayTitle(), nil
+}
+
+// SetActiveTerminal tells the backend which tab is shown so the window can
+// carry its title, -1 means none.
+func (a *App) SetActiveTerminal(id int) {
+	terminals := a.ctx.Value(TerminalsKey).(*Terminals)
+	terminals.mutex.Lock()
+	terminals.active = id
+	terminals.mutex.Unlock()
+	updateWindowTitle(a.ctx)
+}
+
+// updateWindowTitle shows the active terminal's title together with the
+// combined progress of all terminals.
+func updateWindowTitle(ctx context.Context) {
+	terminals := ctx.Value(TerminalsKey).(*Terminals)
+	terminals.mutex.Lock()
+	term := terminals.terminals[terminals.active]
+	terminals.mutex.Unlock()
+
+	title := appTitle
+	if term != nil {
+		title = term.displayTitle()
+	}
+	runtime.WindowSetTitle(ctx, title+progressSuffix(aggregateProgress(ctx)))
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// editors save in several steps, a change is only picked up once the files
+// stayed the same for a whole interval
+const configPollInterval = 500 * time.Millisecond
+
+type fileStamp struct {
+	modTime int64
+	size    int64
+}
+
+// ConfigChange is sent to the frontend with every good config edit.
+type ConfigChange struct {
+	Config    *Config  `json:"config"`
+	Added     []string `json:"added"` // profile names
+	Removed   []string `json:"removed"`
+	Changed   []string `json:"changed"`
+	Shortcuts bool     `json:"shortcuts"`
+	Fonts     bool     `json:"fonts"`
+}
+
+func statFiles(files []string) map[string]fileStamp {
+	stamps := make(map[string]fileStamp, len(files))
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			stamps[file] = fileStamp{info.ModTime().UnixNano(), info.Size()}
+		}
+	}
+	return stamps
+}
+
+// watchConfig polls the config files and reloads them after edits.
+func (a *App) watchConfig(files []string) {
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+	stamps := statFiles(files)
+	pending := false
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := statFiles(files)
+		if !maps.Equal(current, stamps) {
+			stamps = current
+			pending = true
+			continue
+		}
+		if !pending {
+			continue
+		}
+		pending = false
+		if reloaded := a.reloadConfig(); reloaded != nil {
+			files = reloaded
+			stamps = statFiles(files)
+		}
+	}
+}
+
+// reloadConfig applies a good config and keeps the last good one otherwise.
+func (a *App) reloadConfig() []string {
+	config, files, err := a.loadConfig()
+	if err != nil {
+		logger.Println(err)
+		runtime.EventsEmit(a.ctx, "config:error", err.Error())
+		return files
+	}
+
+	a.configMutex.Lock()
+	previous := a.config
+	a.config = config
+	a.configMutex.Unlock()
+	a.loginEnv.configure(config.LoginShellEnv, a.loginEnvCacheFile())
+	a.configureAutomation(config.Automation)
+
+	if !reflect.DeepEqual(previous, config) {
+		runtime.EventsEmit(a.ctx, "config", diffConfig(previous, config))
+	}
+	return files
+}
+
+func diffConfig(previous *Config, config *Config) ConfigChange {
+	change := ConfigChange{
+		Config:  config,
+		Added:   []string{},
+		Removed: []string{},
+		Changed: []string{},
+	}
+	if previous == nil {
+		previous = &Config{}
+	}
+
+	old := make(map[string]Profile, len(previous.Profiles))
+	for _, profile := range previous.Profiles {
+		old[profile.Name] = profile
+	}
+	for _, profile := range config.Profiles {
+		before, ok := old[profile.Name]
+		if !ok {
+			change.Added = append(change.Added, profile.Name)
+		} else if !reflect.DeepEqual(before, profile) {
+			change.Changed = append(change.Changed, profile.Name)
+		}
+		delete(old, profile.Name)
+	}
+	for _, profile := range previous.Profiles {
+		if _, ok := old[profile.Name]; ok {
+			change.Removed = append(change.Removed, profile.Name)
+		}
+	}
+
+	change.Shortcuts = previous.DefaultScope != config.DefaultScope ||
+		!reflect.DeepEqual(previous.Shortcuts, config.Shortcuts)
+	change.Fonts = !reflect.DeepEqual(previous.Fonts, config.Fonts)
+	return change
+}
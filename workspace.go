@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// workspaceFile is looked up from a directory and its parents, e.g. in the
+// root of a repository.
+const workspaceFile = ".term2-workspace"
+
+// Workspace is a set of terminals opened together.
+type Workspace struct {
+	Terminals []WorkspaceTerminal `json:"terminals"`
+}
+
+// WorkspaceTerminal is a tab of a workspace, a relative cwd is resolved
+// against the directory of the workspace file.
+type WorkspaceTerminal struct {
+	Profile string             `json:"profile,omitempty"` // the default profile when empty
+	Cwd     string             `json:"cwd,omitempty"`
+	Title   string             `json:"title,omitempty"`
+	Env     map[string]*string `json:"env,omitempty"` // on top of the profile's
+	Input   string             `json:"input,omitempty"`
+	Command []string           `json:"command,omitempty"`
+}
+
+func (a *App) workspacesDir() string {
+	return filepath.Join(a.paths.Config, "workspaces")
+}
+
+// workspacePath finds the file of a workspace. A plain name is a file in the
+// workspaces directory, a path is resolved against dir and may point at a
+// directory with a .term2-workspace and no name at all searches dir and its
+// parents for one.
+func (a *App) workspacePath(name string, dir string) (string, error) {
+	if name == "" {
+		for current := dir; current != ""; {
+			file := filepath.Join(current, workspaceFile)
+			if _, err := os.Stat(file); err == nil {
+				return file, nil
+			}
+			parent := filepath.Dir(current)
+			if parent == current {
+				break
+			}
+			current = parent
+		}
+		return "", fmt.Errorf("no %s in %s or its parents", workspaceFile, dir)
+	}
+
+	if !strings.ContainsAny(name, `/\`) && filepath.Ext(name) == "" && name != "." && name != ".." {
+		return filepath.Join(a.workspacesDir(), name+".json"), nil
+	}
+	if !filepath.IsAbs(name) && dir != "" {
+		name = filepath.Join(dir, name)
+	}
+	if info, err := os.Stat(name); err == nil && info.IsDir() {
+		name = filepath.Join(name, workspaceFile)
+	}
+	return name, nil
+}
+
+func readWorkspace(file string) (*Workspace, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseJSONC(&jsonSource{file: file, data: data})
+	if err != nil {
+		return nil, err
+	}
+	workspace := &Workspace{}
+	decoder := newJSONDecoder()
+	decoder.decode(root, reflect.ValueOf(workspace).Elem(), "$")
+	if len(decoder.errors) > 0 {
+		return nil, decoder.errors
+	}
+	for i, term := range workspace.Terminals {
+		if _, err := syntaxExpander.expand(term.Cwd); err != nil {
+			decoder.errorPath(fmt.Sprintf("$.terminals[%d].cwd", i), "%v", err)
+		}
+		for _, key := range sortedKeys(term.Env) {
+			if !validName(key) {
+				decoder.errorPath(fmt.Sprintf("$.terminals[%d].env.%s", i, key), "invalid variable name %q", key)
+			}
+		}
+	}
+	if len(decoder.errors) > 0 {
+		return nil, decoder.errors
+	}
+	if len(workspace.Terminals) == 0 {
+		return nil, fmt.Errorf("%s has no terminals", file)
+	}
+	return workspace, nil
+}
+
+// requests turns the terminals of a workspace read from file into requests
+// for the frontend.
+func (w *Workspace) requests(file string) []TerminalRequest {
+	dir := filepath.Dir(file)
+	requests := make([]TerminalRequest, 0, len(w.Terminals))
+	for _, term := range w.Terminals {
+		request := TerminalRequest{
+			Profile: term.Profile,
+			Title:   term.Title,
+			Env:     term.Env,
+			Command: term.Command,
+		}
+		if term.Cwd != "" {
+			cwd := term.Cwd
+			// ~ and variables are expanded when the terminal starts
+			if !filepath.IsAbs(cwd) && !strings.HasPrefix(cwd, "~") && !strings.HasPrefix(cwd, "$") {
+				cwd = filepath.Join(dir, cwd)
+			}
+			request.Cwd = &cwd
+		}
+		if term.Input != "" {
+			request.Input = &term.Input
+		}
+		requests = append(requests, request)
+	}
+	return requests
+}
+
+func (a *App) loadWorkspace(name string, dir string) ([]TerminalRequest, error) {
+	file, err := a.workspacePath(name, dir)
+	if err != nil {
+		return nil, err
+	}
+	workspace, err := readWorkspace(file)
+	if err != nil {
+		return nil, err
+	}
+	logger.Printf("Opening workspace %s\n", file)
+	requests := workspace.requests(file)
+	for i := range requests {
+		requests[i].ExpandCwd = true
+	}
+	return requests, nil
+}
+
+// OpenWorkspace returns the tabs of a workspace in order, an empty name
+// looks for a .term2-workspace from the directory of terminal from.
+func (a *App) OpenWorkspace(name string, from int) ([]TerminalRequest, error) {
+	dir := ""
+	if from != -1 {
+		dir = a.terminalCwd(from)
+	}
+	if dir == "" {
+		dir = a.defaultCwd(TerminalConfig{})
+	}
+	return a.loadWorkspace(name, dir)
+}
+
+// SaveWorkspace writes the given terminals in tab order as a workspace and
+// returns the file. Without a name a save dialog asks for the file, an empty
+// file means it was cancelled.
+func (a *App) SaveWorkspace(name string, ids []int) (string, error) {
+	workspace := Workspace{Terminals: make([]WorkspaceTerminal, 0, len(ids))}
+	for _, id := range ids {
+		term, err := a.terminal(id)
+		if err != nil {
+			return "", err
+		}
+		workspace.Terminals = append(workspace.Terminals, a.workspaceTerminal(term))
+	}
+	if len(workspace.Terminals) == 0 {
+		return "", errors.New("there are no terminals to save")
+	}
+
+	if err := os.MkdirAll(a.workspacesDir(), 0755); err != nil {
+		return "", err
+	}
+	var file string
+	var err error
+	if name != "" {
+		file, err = a.workspacePath(name, a.defaultCwd(TerminalConfig{}))
+	} else {
+		file, err = runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+			Title:            "Save workspace",
+			DefaultDirectory: a.workspacesDir(),
+			DefaultFilename:  "workspace.json",
+			Filters:          []runtime.FileFilter{{DisplayName: "Workspaces", Pattern: "*.json;" + workspaceFile}},
+		})
+	}
+	if err != nil || file == "" {
+		return "", err
+	}
+
+	data, err := json.MarshalIndent(workspace, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(file, append(data, '\n'), 0644); err != nil {
+		return "", err
+	}
+	logger.Printf("Saved workspace %s\n", file)
+	return file, nil
+}
+
+// workspaceTerminal describes how a terminal was started, in the directory
+// it is in now. Only env that differs from the profile is kept.
+func (a *App) workspaceTerminal(term *Terminal) WorkspaceTerminal {
+	term.mutex.Lock()
+	saved := WorkspaceTerminal{
+		Profile: term.config.Profile,
+		Title:   term.config.Title,
+	}
+	term.mutex.Unlock()
+	saved.Cwd = a.terminalCwd(term.id)
+	if term.config.Input != nil {
+		saved.Input = *term.config.Input
+	}
+	if term.config.Verbatim {
+		saved.Command = append([]string{term.config.Command}, term.config.Args...)
+	}
+
+	profile, _ := a.profile(term.config.Profile)
+	for key, value := range term.config.Env {
+		if !reflect.DeepEqual(profile.Env[key], value) {
+			if saved.Env == nil {
+				saved.Env = make(map[string]*string)
+			}
+			saved.Env[key] = value
+		}
+	}
+	return saved
+}